@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"findApi/domain"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader names the request header identifying who makes the changes of
+// a request, recorded as createdBy and updatedBy. There is no authentication
+// yet, so it is taken on trust.
+const ActorHeader = "X-Actor"
+
+// maxActorLength is the longest actor, in bytes, recorded on a change
+const maxActorLength = 128
+
+// actorOf returns who makes the changes of a request, or domain.ActorAnonymous without the header
+func actorOf(ctx *gin.Context) string {
+	actor := strings.TrimSpace(ctx.GetHeader(ActorHeader))
+	if actor == "" {
+		return domain.ActorAnonymous
+	}
+	if len(actor) > maxActorLength {
+		actor = strings.ToValidUTF8(actor[:maxActorLength], "")
+	}
+	return actor
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"findApi/domain"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddressBookHeader names the request header selecting the address book of
+// the caller. Like the actor it is taken on trust until there is
+// authentication. Event streams may name it in the addressBook query
+// parameter instead, as browsers cannot set headers on them.
+const AddressBookHeader = "X-Address-Book"
+
+// maxAddressBookLength is the longest address book name, in bytes
+const maxAddressBookLength = 128
+
+// addressBookOf returns the address book of the caller, or domain.DefaultAddressBook without one
+func addressBookOf(ctx *gin.Context) string {
+	book := strings.TrimSpace(ctx.GetHeader(AddressBookHeader))
+	if book == "" {
+		book = strings.TrimSpace(ctx.Query("addressBook"))
+	}
+	if len(book) > maxAddressBookLength {
+		book = strings.ToValidUTF8(book[:maxAddressBookLength], "")
+	}
+	return domain.AddressBookOf(book)
+}
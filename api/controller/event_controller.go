@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// replayBatchSize is the number of stored events read at a time when a client catches up
+const replayBatchSize = 500
+
+type EventController struct {
+	EventUsecase usecase.EventsUseCase
+	Subscriber   usecase.EventSubscriber
+}
+
+// StreamEvents handles streaming the user events of the caller's address book
+// over Server-Sent Events. A client reconnecting with Last-Event-ID first
+// receives the events it missed. When those are no longer stored the client
+// gets 410 Gone, or a resync event once streaming, and has to sync again
+// from scratch with GET /users/changes.
+func (c *EventController) StreamEvents(ctx *gin.Context) {
+	addressBook := addressBookOf(ctx)
+
+	// Subscribe before replaying so no event falls between the two
+	events, unsubscribe := c.Subscriber.Subscribe(64)
+	defer unsubscribe()
+
+	lastSeq := int64(-1)
+	if lastEventID := ctx.GetHeader("Last-Event-ID"); lastEventID != "" {
+		seq, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || seq < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastSeq = seq
+
+		err = c.EventUsecase.CheckResume(ctx.Request.Context(), lastSeq)
+		if errors.Is(err, usecase.ErrEventsExpired) {
+			ctx.JSON(http.StatusGone, gin.H{"error": "Events after Last-Event-ID are no longer available, resync required"})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve events"})
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	if lastSeq >= 0 {
+		if err := c.replay(ctx, addressBook, &lastSeq); err != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			// Comments keep idle connections open through proxies
+			fmt.Fprint(w, ": ping\n\n")
+			return true
+		case event, ok := <-events:
+			if !ok {
+				// The server is shutting down, the client reconnects with Last-Event-ID
+				return false
+			}
+			switch {
+			case lastSeq >= 0 && event.Seq <= lastSeq:
+				// Already sent, events are delivered at least once
+				return true
+			case lastSeq >= 0 && event.Seq > lastSeq+1:
+				// Events were dropped for this subscriber, read them from
+				// storage, which already holds the event at hand
+				if c.replay(ctx, addressBook, &lastSeq) != nil {
+					return false
+				}
+				lastSeq = max(lastSeq, event.Seq)
+				return true
+			}
+			// Events of other address books are skipped, but still count as seen
+			if domain.AddressBookOf(event.AddressBook) == addressBook {
+				renderEvent(ctx, event)
+			}
+			lastSeq = event.Seq
+			return true
+		}
+	})
+}
+
+// replay sends the stored events of the address book after lastSeq and
+// advances it. When some of them are no longer stored it sends a resync
+// event instead, after which the stream ends.
+func (c *EventController) replay(ctx *gin.Context, addressBook string, lastSeq *int64) error {
+	for {
+		missed, err := c.EventUsecase.FindEventsSince(ctx.Request.Context(), addressBook, *lastSeq, replayBatchSize)
+		if errors.Is(err, usecase.ErrEventsExpired) {
+			ctx.Render(-1, sse.Event{Event: "resync", Data: gin.H{"error": "Missed events are no longer available, resync required"}})
+			return err
+		}
+		if err != nil {
+			ctx.Render(-1, sse.Event{Event: "error", Data: gin.H{"error": "Failed to retrieve events"}})
+			return err
+		}
+
+		for _, event := range missed {
+			renderEvent(ctx, event)
+			*lastSeq = event.Seq
+		}
+		if len(missed) < replayBatchSize {
+			return nil
+		}
+	}
+}
+
+// renderEvent writes a single event with its sequence number as the event id
+func renderEvent(ctx *gin.Context, event *domain.Event) {
+	ctx.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.Seq, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
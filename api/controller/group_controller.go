@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GroupController struct {
+	GroupUsecase usecase.GroupsUseCase
+}
+
+// CreateGroup handles the creation of a new group
+func (c *GroupController) CreateGroup(ctx *gin.Context) {
+	var group domain.Group
+	// Parse the request body to get the group details
+	if err := ctx.ShouldBindJSON(&group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	// Call use case to insert the group
+	createdGroup, err := c.GroupUsecase.CreateGroup(ctx.Request.Context(), &group)
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	// Return the created group with a 201 Created status
+	ctx.JSON(http.StatusCreated, createdGroup)
+}
+
+// FindGroups handles fetching all groups
+func (c *GroupController) FindGroups(ctx *gin.Context) {
+	groups, err := c.GroupUsecase.FindGroups(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve groups"})
+		return
+	}
+
+	// Return the list of groups with a 200 OK status
+	ctx.JSON(http.StatusOK, groups)
+}
+
+// GetGroup handles fetching a group by id
+func (c *GroupController) GetGroup(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	group, err := c.GroupUsecase.GetGroup(ctx.Request.Context(), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve group"})
+		return
+	}
+
+	// Return the group with a 200 OK status
+	ctx.JSON(http.StatusOK, group)
+}
+
+// RenameGroup handles changing the name of a group by id
+func (c *GroupController) RenameGroup(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	var group domain.Group
+	// Parse the request body to get the new name
+	if err := ctx.ShouldBindJSON(&group); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	err = c.GroupUsecase.RenameGroup(ctx.Request.Context(), id, group.Name)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Group already exists"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename group"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Group renamed successfully"})
+}
+
+// DeleteGroup handles removing a group by id. Its members are kept.
+func (c *GroupController) DeleteGroup(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	err = c.GroupUsecase.DeleteGroup(ctx.Request.Context(), id, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete group"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+// AddMembers handles adding users to a group by id
+func (c *GroupController) AddMembers(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+
+	var req domain.MembersReq
+	// Parse the request body to get the user ids
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if len(req.UserIDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one user id is required"})
+		return
+	}
+	userIDs := make([]primitive.ObjectID, 0, len(req.UserIDs))
+	for _, hex := range req.UserIDs {
+		userID, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id " + hex})
+			return
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	err = c.GroupUsecase.AddMembers(ctx.Request.Context(), id, userIDs, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Group or user not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add members"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Members added successfully"})
+}
+
+// RemoveMember handles taking a user out of a group by group and user id
+func (c *GroupController) RemoveMember(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	err = c.GroupUsecase.RemoveMember(ctx.Request.Context(), id, userID, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
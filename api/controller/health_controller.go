@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"findApi/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct {
+	HealthUsecase usecase.HealthUseCase
+}
+
+// Live handles the liveness check, which only tells that the process is up
+// and serving requests
+func (c *HealthController) Live(ctx *gin.Context) {
+	// Return the liveness with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Ready handles the readiness check of the load balancer, reporting the
+// outcome of every check. It fails while the server starts up, once it starts
+// shutting down and whenever a dependency is unhealthy.
+func (c *HealthController) Ready(ctx *gin.Context) {
+	report := c.HealthUsecase.Ready(ctx.Request.Context())
+	if !report.Ready {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	// Return the readiness report with a 200 OK status
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxInteractionPageSize is the most interactions returned in one page
+const maxInteractionPageSize = 100
+
+type InteractionController struct {
+	InteractionUsecase usecase.InteractionsUseCase
+}
+
+// AddInteraction handles recording a call, meeting or email with a user by id
+func (c *InteractionController) AddInteraction(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var interaction domain.Interaction
+	// Parse the request body to get the interaction details
+	if err := ctx.ShouldBindJSON(&interaction); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// Call the use case to record the interaction
+	created, err := c.InteractionUsecase.AddInteraction(ctx.Request.Context(), id, &interaction, actorOf(ctx))
+	if errors.Is(err, usecase.ErrInvalidInteraction) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interaction"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add interaction"})
+		return
+	}
+
+	// Return the recorded interaction with a 201 Created status
+	ctx.JSON(http.StatusCreated, created)
+}
+
+// FindInteractions handles fetching a page of the interactions with a user by
+// id, newest first. The next page is requested with the returned cursor.
+func (c *InteractionController) FindInteractions(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	limit, err := strconv.ParseInt(ctx.DefaultQuery("limit", "20"), 10, 64)
+	if err != nil || limit < 1 || limit > maxInteractionPageSize {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	page, err := c.InteractionUsecase.FindInteractions(ctx.Request.Context(), id, ctx.Query("cursor"), limit)
+	if errors.Is(err, usecase.ErrInvalidCursor) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve interactions"})
+		return
+	}
+
+	// Return the page of interactions with a 200 OK status
+	ctx.JSON(http.StatusOK, page)
+}
+
+// DeleteInteraction handles removing an interaction with a user by id
+func (c *InteractionController) DeleteInteraction(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	interactionID, err := primitive.ObjectIDFromHex(ctx.Param("interactionId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interaction id"})
+		return
+	}
+
+	err = c.InteractionUsecase.DeleteInteraction(ctx.Request.Context(), id, interactionID, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete interaction"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Interaction deleted successfully"})
+}
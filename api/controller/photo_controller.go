@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"errors"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PhotoController struct {
+	UserUsecase usecase.UsersUseCase
+	Env         *bootstrap.Env
+}
+
+// UploadPhoto handles setting the photo of a user by id. The image is sent
+// as the raw request body or as the "photo" field of a multipart form.
+func (c *PhotoController) UploadPhoto(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.Env.PHOTO_MAX_BYTES)
+	data, err := readPhoto(ctx)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Photo is too large"})
+		return
+	}
+	if err != nil || len(data) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// Call the use case to store the photo
+	photo, err := c.UserUsecase.SetPhoto(ctx.Request.Context(), id, data, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if errors.Is(err, usecase.ErrUnsupportedPhoto) {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Photo must be a JPEG, PNG or GIF image"})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidPhoto) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store photo"})
+		return
+	}
+
+	// Return the stored photo with a 200 OK status
+	ctx.JSON(http.StatusOK, photo)
+}
+
+// readPhoto reads the uploaded image from a multipart form or the raw body
+func readPhoto(ctx *gin.Context) ([]byte, error) {
+	if !strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		return io.ReadAll(ctx.Request.Body)
+	}
+
+	header, err := ctx.FormFile("photo")
+	if err != nil {
+		return nil, err
+	}
+	var file multipart.File
+	if file, err = header.Open(); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// GetPhoto handles fetching the photo of a user by id, as a thumbnail when a size is given
+func (c *PhotoController) GetPhoto(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	size, err := strconv.Atoi(ctx.DefaultQuery("size", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+		return
+	}
+
+	data, contentType, err := c.UserUsecase.GetPhoto(ctx.Request.Context(), id, size)
+	if errors.Is(err, usecase.ErrInvalidPhotoSize) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, repository.ErrBlobNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve photo"})
+		return
+	}
+
+	// Return the image with a 200 OK status
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// DeletePhoto handles removing the photo of a user by id
+func (c *PhotoController) DeletePhoto(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	err = c.UserUsecase.DeletePhoto(ctx.Request.Context(), id, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, repository.ErrBlobNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Photo not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete photo"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Photo deleted successfully"})
+}
+
+// ExportVCard handles exporting a user by id as a vCard with its photo
+func (c *PhotoController) ExportVCard(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	card, err := c.UserUsecase.ExportVCard(ctx.Request.Context(), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user"})
+		return
+	}
+
+	// Return the vCard with a 200 OK status
+	ctx.Header("Content-Disposition", `attachment; filename="`+id.Hex()+`.vcf"`)
+	ctx.Data(http.StatusOK, "text/vcard; charset=utf-8", card)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PrivacyController struct {
+	PrivacyUsecase usecase.PrivacyUseCase
+}
+
+// ExportSubject handles an access request, returning everything kept about
+// the username or phone in the request body as a downloadable JSON bundle.
+// The identifiers are taken from the body so they stay out of access logs.
+func (c *PrivacyController) ExportSubject(ctx *gin.Context) {
+	var subject domain.SubjectReq
+	if err := ctx.ShouldBindJSON(&subject); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	export, err := c.PrivacyUsecase.ExportSubject(ctx.Request.Context(), &subject)
+	if errors.Is(err, usecase.ErrInvalidSubject) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username or phone is required"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No records found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export records"})
+		return
+	}
+
+	// Return the export bundle as an attachment with a 200 OK status
+	ctx.Header("Content-Disposition", `attachment; filename="subject-export.json"`)
+	ctx.JSON(http.StatusOK, export)
+}
+
+// EraseSubject handles an erasure request, permanently erasing every user with
+// the username or phone in the request body together with everything kept about them
+func (c *PrivacyController) EraseSubject(ctx *gin.Context) {
+	var subject domain.SubjectReq
+	if err := ctx.ShouldBindJSON(&subject); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	report, err := c.PrivacyUsecase.EraseSubject(ctx.Request.Context(), &subject)
+	if errors.Is(err, usecase.ErrInvalidSubject) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Username or phone is required"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "No records found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to erase records"})
+		return
+	}
+
+	// Return the erased users with a 200 OK status
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RelationshipController struct {
+	RelationshipUsecase usecase.RelationshipsUseCase
+}
+
+// AddRelationship handles recording that another user is related to a user by id
+func (c *RelationshipController) AddRelationship(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	var req domain.RelationshipReq
+	// Parse the request body to get the relationship details
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	to, err := primitive.ObjectIDFromHex(req.To)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid related user id"})
+		return
+	}
+
+	// Call the use case to record the relationship
+	relationships, err := c.RelationshipUsecase.AddRelationship(ctx.Request.Context(), id, to, req.Type, req.Bidirectional)
+	if errors.Is(err, usecase.ErrInvalidRelationship) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add relationship"})
+		return
+	}
+
+	// Return the recorded relationships with a 201 Created status
+	ctx.JSON(http.StatusCreated, relationships)
+}
+
+// FindRelationships handles fetching the relationships of a user by id, optionally of one type
+func (c *RelationshipController) FindRelationships(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	relationships, err := c.RelationshipUsecase.FindRelationships(ctx.Request.Context(), id, ctx.Query("type"))
+	if errors.Is(err, usecase.ErrInvalidRelationship) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship type"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve relationships"})
+		return
+	}
+
+	// Return the list of relationships with a 200 OK status
+	ctx.JSON(http.StatusOK, relationships)
+}
+
+// DeleteRelationship handles removing a relationship of a user by id, and
+// the reverse edge too with bidirectional=true
+func (c *RelationshipController) DeleteRelationship(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	relationshipID, err := primitive.ObjectIDFromHex(ctx.Param("relationshipId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship id"})
+		return
+	}
+	bidirectional, err := strconv.ParseBool(ctx.DefaultQuery("bidirectional", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bidirectional flag"})
+		return
+	}
+
+	err = c.RelationshipUsecase.DeleteRelationship(ctx.Request.Context(), id, relationshipID, bidirectional)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Relationship not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete relationship"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Relationship deleted successfully"})
+}
+
+// FindRelated handles following the relationships of a user by id, optionally
+// of one type, up to depth edges away
+func (c *RelationshipController) FindRelated(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	depth, err := strconv.Atoi(ctx.DefaultQuery("depth", "1"))
+	if err != nil || depth < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid depth"})
+		return
+	}
+
+	related, err := c.RelationshipUsecase.FindRelated(ctx.Request.Context(), id, ctx.Query("type"), depth)
+	if errors.Is(err, usecase.ErrInvalidRelationship) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relationship type"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve related users"})
+		return
+	}
+
+	// Return the list of related users with a 200 OK status
+	ctx.JSON(http.StatusOK, related)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"findApi/bootstrap"
+	"findApi/usecase"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxReminderDays is the furthest ahead upcoming reminders can be listed
+const maxReminderDays = 366
+
+type ReminderController struct {
+	UserUsecase usecase.UsersUseCase
+	Env         *bootstrap.Env
+}
+
+// UpcomingReminders handles listing the birthdays and anniversaries in the
+// next days, counted from today in the time zone given by tz
+func (c *ReminderController) UpcomingReminders(ctx *gin.Context) {
+	days, err := strconv.Atoi(ctx.DefaultQuery("days", "30"))
+	if err != nil || days < 0 || days > maxReminderDays {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+		return
+	}
+	location, err := time.LoadLocation(ctx.DefaultQuery("tz", c.Env.REMINDER_TIMEZONE))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time zone"})
+		return
+	}
+
+	reminders, err := c.UserUsecase.UpcomingReminders(ctx.Request.Context(), time.Now().In(location), days)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reminders"})
+		return
+	}
+
+	// Return the list of reminders with a 200 OK status
+	ctx.JSON(http.StatusOK, reminders)
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxRetentionDays is the longest retention a rule may be set to, a century
+const maxRetentionDays = 36500
+
+type RetentionController struct {
+	RetentionUsecase usecase.RetentionUseCase
+}
+
+// PreviewRetention handles reporting what the retention rules of the
+// caller's address book would remove right now, without removing anything
+func (c *RetentionController) PreviewRetention(ctx *gin.Context) {
+	report, err := c.RetentionUsecase.PreviewPolicy(ctx.Request.Context(), addressBookOf(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to preview retention"})
+		return
+	}
+
+	// Return the dry run report with a 200 OK status
+	ctx.JSON(http.StatusOK, report)
+}
+
+// GetRetentionPolicy handles fetching the retention policy of the caller's
+// address book, which is the default one unless it has its own
+func (c *RetentionController) GetRetentionPolicy(ctx *gin.Context) {
+	addressBook := addressBookOf(ctx)
+	policy, err := c.RetentionUsecase.GetPolicy(ctx.Request.Context(), addressBook)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve retention policy"})
+		return
+	}
+
+	// Return the policy in days with a 200 OK status
+	days := policy.Days()
+	days.AddressBook = addressBook
+	ctx.JSON(http.StatusOK, days)
+}
+
+// SetRetentionPolicy handles giving the caller's address book a retention policy of its own
+func (c *RetentionController) SetRetentionPolicy(ctx *gin.Context) {
+	var days domain.RetentionDays
+	// Parse the request body to get the rules in days
+	if err := ctx.ShouldBindJSON(&days); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	for _, rule := range []float64{days.StaleUsersDays, days.TrashDays, days.RevisionsDays} {
+		if math.IsNaN(rule) || rule < 0 || rule > maxRetentionDays {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Retention rules must be between 0 and 36500 days"})
+			return
+		}
+	}
+
+	addressBook := addressBookOf(ctx)
+	policy := days.Policy(addressBook)
+	if err := c.RetentionUsecase.SetPolicy(ctx.Request.Context(), &policy); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set retention policy"})
+		return
+	}
+
+	// Return the stored policy with a 200 OK status
+	ctx.JSON(http.StatusOK, policy.Days())
+}
+
+// DeleteRetentionPolicy handles removing the retention policy of the
+// caller's address book, which falls back to the default one
+func (c *RetentionController) DeleteRetentionPolicy(ctx *gin.Context) {
+	err := c.RetentionUsecase.DeletePolicy(ctx.Request.Context(), addressBookOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Address book has no retention policy of its own"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete retention policy"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted successfully"})
+}
@@ -1,14 +1,20 @@
 package controller
 
 import (
+	"errors"
 	"findApi/bootstrap"
 	"findApi/domain"
 	"findApi/internal/encryptutil"
 	"findApi/usecase"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type UserController struct {
@@ -18,21 +24,31 @@ type UserController struct {
 
 // CreateUser handles the creation of a new user
 func (c *UserController) CreateUser(ctx *gin.Context) {
-	var user domain.User
-	// Parse the request body to get the user details
-	if err := ctx.ShouldBindJSON(&user); err != nil {
+	var req domain.CreateReq
+	// Parse the request body to get the user details, leaving the server-managed fields alone
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
-	phoneExist,_ := c.UserUsecase.GetUserByPhone(user.Phone)
-	usernameExist,_ := c.UserUsecase.GetUserByUsername(user.Username)
+	user := req.User()
+	user.AddressBook = addressBookOf(ctx)
+	phoneExist,_ := c.UserUsecase.GetUserByPhone(ctx.Request.Context(), user.Phone)
+	usernameExist,_ := c.UserUsecase.GetUserByUsername(ctx.Request.Context(), user.Username)
 	if phoneExist != nil && usernameExist != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Phone or username already exists"})
 		return
 	}
 
 	// Call use case to insert the user
-	createdUser, err := c.UserUsecase.CreateUser(&user)
+	createdUser, err := c.UserUsecase.CreateUser(ctx.Request.Context(), user, actorOf(ctx))
+	if errors.Is(err, usecase.ErrInvalidTag) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+		return
+	}
+	if errors.Is(err, usecase.ErrInvalidDate) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid birthday or anniversary"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user" + err.Error()})
 		return
@@ -47,7 +63,7 @@ func (c *UserController) GetUserByUsername(ctx *gin.Context) {
 	username := ctx.Param("username") // Get the username from the URL path
 
 	// Call use case to fetch user by username
-	user, err := c.UserUsecase.GetUserByUsername(username)
+	user, err := c.UserUsecase.GetUserByUsername(ctx.Request.Context(), username)
 	if err != nil || user == nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -62,7 +78,7 @@ func (c *UserController) GetUserByPhone(ctx *gin.Context) {
 	phone := ctx.Param("phone") // Get the phone number from the URL path
 
 	// Call use case to fetch user by phone number
-	user, err := c.UserUsecase.GetUserByPhone(phone)
+	user, err := c.UserUsecase.GetUserByPhone(ctx.Request.Context(), phone)
 	if err != nil || user == nil {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -72,6 +88,88 @@ func (c *UserController) GetUserByPhone(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, user)
 }
 
+// LookupUsers handles fetching the users with any of the usernames or phone
+// numbers in the request body, one or many at once
+func (c *UserController) LookupUsers(ctx *gin.Context) {
+	var req domain.LookupReq
+	// Parse the request body to get the identifiers, kept for LookupCost having read it before
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if req.Size() == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one username or phone is required"})
+		return
+	}
+	if req.Size() > c.Env.LOOKUP_MAX_BATCH {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "At most " + strconv.Itoa(c.Env.LOOKUP_MAX_BATCH) + " usernames and phones can be looked up at once"})
+		return
+	}
+
+	// Call use case to look the users up
+	result, err := c.UserUsecase.LookupUsers(ctx.Request.Context(), req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up users"})
+		return
+	}
+
+	// Return the users found and the identifiers matching none with a 200 OK status
+	ctx.JSON(http.StatusOK, result)
+}
+
+// LookupCost is what a lookup costs in the rate limit: one token for every
+// identifier in the request body, and one for a body without any
+func LookupCost(ctx *gin.Context) float64 {
+	var req domain.LookupReq
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil || req.Size() == 0 {
+		return 1
+	}
+	return float64(req.Size())
+}
+
+// DiscoverContacts handles telling which of the hashed phone numbers in the
+// request body are in the book, by whole hashes or by their bucket prefixes
+func (c *UserController) DiscoverContacts(ctx *gin.Context) {
+	var req domain.DiscoveryReq
+	// Parse the request body to get the hashes, kept for DiscoveryCost having read it before
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if req.Size() == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one hash or prefix is required"})
+		return
+	}
+	if req.Size() > c.Env.LOOKUP_MAX_BATCH {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "At most " + strconv.Itoa(c.Env.LOOKUP_MAX_BATCH) + " hashes and prefixes can be discovered at once"})
+		return
+	}
+
+	// Call use case to match the hashes
+	result, err := c.UserUsecase.DiscoverContacts(ctx.Request.Context(), req)
+	if errors.Is(err, usecase.ErrInvalidHash) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Hashes must be hex-encoded SHA-256 and prefixes " + strconv.Itoa(domain.DiscoveryBucketLength) + " hex digits"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to discover contacts"})
+		return
+	}
+
+	// Return the matching hashes and the buckets with a 200 OK status
+	ctx.JSON(http.StatusOK, result)
+}
+
+// DiscoveryCost is what a discovery costs in the rate limit: one token for
+// every hash and prefix in the request body, and one for a body without any
+func DiscoveryCost(ctx *gin.Context) float64 {
+	var req domain.DiscoveryReq
+	if err := ctx.ShouldBindBodyWith(&req, binding.JSON); err != nil || req.Size() == 0 {
+		return 1
+	}
+	return float64(req.Size())
+}
+
 // UpdateUser handles updating a user by username, phone, or both
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	var req domain.UpdateReq
@@ -80,8 +178,8 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
-	if req.NewUsername == "" && req.NewPhone == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "New username or phone is required"})
+	if req.NewUsername == "" && req.NewPhone == "" && req.Birthday == nil && req.Anniversary == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "New username, phone, birthday or anniversary is required"})
 		return
 	}
 
@@ -111,12 +209,18 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 	}
 
 	updateUser := domain.User{
-		Username: req.NewUsername,
-		Phone:    req.NewPhone,
+		Username:    req.NewUsername,
+		Phone:       req.NewPhone,
+		Birthday:    req.Birthday,
+		Anniversary: req.Anniversary,
 	}
 
 	// Call the use case to update the user
-	err := c.UserUsecase.UpdateUser(filter, &updateUser)
+	err := c.UserUsecase.UpdateUser(ctx.Request.Context(), filter, &updateUser, actorOf(ctx))
+	if errors.Is(err, usecase.ErrInvalidDate) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid birthday or anniversary"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
@@ -127,7 +231,7 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 }
 
 
-// DeleteUser handles deleting a user by username or phone
+// DeleteUser handles moving a user to the trash by username or phone
 func (c *UserController) DeleteUser(ctx *gin.Context) {
 	var user domain.User
 	// Parse the request body to get the user details
@@ -159,7 +263,7 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 	}
 
 	// Call the use case to delete the user
-	err := c.UserUsecase.DeleteUser(filter)
+	err := c.UserUsecase.DeleteUser(ctx.Request.Context(), filter, actorOf(ctx))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
@@ -169,14 +273,300 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
-// FindAllUsers handles fetching all users
+// FindAllUsers handles fetching all users, optionally filtered by group, tags
+// and favorite flag and sorted by last contact, creation or last change. With
+// facets=true the users come with their tag counts.
 func (c *UserController) FindAllUsers(ctx *gin.Context) {
-	users, err := c.UserUsecase.FindAllUsers()
+	filter := domain.UserFilter{Tags: ctx.QueryArray("tag"), Sort: ctx.Query("sort")}
+	if filter.Sort != "" && !slices.Contains(domain.UserSorts, filter.Sort) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort"})
+		return
+	}
+	if group := ctx.Query("group"); group != "" {
+		groupID, err := primitive.ObjectIDFromHex(group)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group id"})
+			return
+		}
+		filter.GroupID = &groupID
+	}
+	if favorite := ctx.Query("favorite"); favorite != "" {
+		isFavorite, err := strconv.ParseBool(favorite)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid favorite flag"})
+			return
+		}
+		filter.Favorite = &isFavorite
+	}
+	withFacets, err := strconv.ParseBool(ctx.DefaultQuery("facets", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid facets flag"})
+		return
+	}
+
+	users, err := c.UserUsecase.FindAllUsers(ctx.Request.Context(), filter)
+	if errors.Is(err, usecase.ErrInvalidTag) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
 		return
 	}
+	if !withFacets {
+		// Return the list of users with a 200 OK status
+		ctx.JSON(http.StatusOK, users)
+		return
+	}
+
+	facets, err := c.UserUsecase.FacetUsers(ctx.Request.Context(), filter)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count tags"})
+		return
+	}
+
+	// Return the list of users and their facets with a 200 OK status
+	ctx.JSON(http.StatusOK, domain.UserList{Users: users, Facets: facets})
+}
+
+// TagUsers handles adding and removing tags and setting the favorite flag on several users
+func (c *UserController) TagUsers(ctx *gin.Context) {
+	var req domain.TagsReq
+	// Parse the request body to get the users and their changes
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if len(req.UserIDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one user id is required"})
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 && req.Favorite == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to change"})
+		return
+	}
+	userIDs := make([]primitive.ObjectID, 0, len(req.UserIDs))
+	for _, hex := range req.UserIDs {
+		userID, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id " + hex})
+			return
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	// Call the use case to tag the users
+	err := c.UserUsecase.TagUsers(ctx.Request.Context(), userIDs, req.Add, req.Remove, req.Favorite, actorOf(ctx))
+	if errors.Is(err, usecase.ErrInvalidTag) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag users"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Users tagged successfully"})
+}
+
 
-	// Return the list of users with a 200 OK status
+// FindTrash handles fetching all users in the trash
+func (c *UserController) FindTrash(ctx *gin.Context) {
+	users, err := c.UserUsecase.FindTrash(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trash"})
+		return
+	}
+
+	// Return the list of trashed users with a 200 OK status
 	ctx.JSON(http.StatusOK, users)
 }
+
+// RestoreUser handles moving a user out of the trash by id
+func (c *UserController) RestoreUser(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	// Call the use case to restore the user
+	err = c.UserUsecase.RestoreUser(ctx.Request.Context(), id, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found in trash"})
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Phone or username already exists"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "User restored successfully"})
+}
+
+// FindRevisions handles fetching the prior versions of a user by id
+func (c *UserController) FindRevisions(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	revisions, err := c.UserUsecase.FindRevisions(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve revisions"})
+		return
+	}
+
+	// Return the list of revisions with a 200 OK status
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// RevertUser handles reverting a user to a prior version by id and revision number
+func (c *UserController) RevertUser(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+	rev, err := strconv.Atoi(ctx.Param("rev"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision"})
+		return
+	}
+
+	// Call the use case to revert the user
+	err = c.UserUsecase.RevertUser(ctx.Request.Context(), id, rev, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User or revision not found"})
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Phone or username already exists"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert user"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "User reverted successfully"})
+}
+
+// syncPageSize is the maximum number of changes returned by a single sync request
+const syncPageSize = 500
+
+// GetChanges handles fetching the user changes since a sync token. Without a
+// token all users are returned together with the token for the next sync.
+func (c *UserController) GetChanges(ctx *gin.Context) {
+	result, err := c.UserUsecase.SyncUsers(ctx.Request.Context(), ctx.Query("since"), syncPageSize)
+	if errors.Is(err, usecase.ErrInvalidSyncToken) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sync token"})
+		return
+	}
+	if errors.Is(err, usecase.ErrSyncTokenExpired) {
+		// The client has to drop its copy and sync again without a token
+		ctx.JSON(http.StatusGone, gin.H{"error": "Sync token expired, full resync required", "resync": true})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve changes"})
+		return
+	}
+
+	// Return the changes with a 200 OK status
+	ctx.JSON(http.StatusOK, result)
+}
+
+// FindDuplicates handles fetching pairs of users that likely describe the same person
+func (c *UserController) FindDuplicates(ctx *gin.Context) {
+	minScore := c.Env.DUPLICATE_MIN_SCORE
+	if raw := ctx.Query("minScore"); raw != "" {
+		score, err := strconv.ParseFloat(raw, 64)
+		if err != nil || score < 0 || score > 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "minScore must be between 0 and 1"})
+			return
+		}
+		minScore = score
+	}
+
+	candidates, err := c.UserUsecase.FindDuplicates(ctx.Request.Context(), minScore)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find duplicates"})
+		return
+	}
+
+	// Return the duplicate candidates with a 200 OK status
+	ctx.JSON(http.StatusOK, candidates)
+}
+
+// MergeUsers handles folding duplicate users into a primary user
+func (c *UserController) MergeUsers(ctx *gin.Context) {
+	var req domain.MergeReq
+	// Parse the request body to get the merge details
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	primaryID, err := primitive.ObjectIDFromHex(req.PrimaryID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid primary id"})
+		return
+	}
+	if len(req.DuplicateIDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one duplicate id is required"})
+		return
+	}
+	duplicateIDs := make([]primitive.ObjectID, 0, len(req.DuplicateIDs))
+	for _, hex := range req.DuplicateIDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil || id == primaryID || slices.Contains(duplicateIDs, id) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duplicate id " + hex})
+			return
+		}
+		duplicateIDs = append(duplicateIDs, id)
+	}
+
+	// Fall back to the configured rule for fields without one
+	usernameRule, phoneRule := req.Rules.Username, req.Rules.Phone
+	if usernameRule == "" {
+		usernameRule = c.Env.MERGE_CONFLICT_RULE
+	}
+	if phoneRule == "" {
+		phoneRule = c.Env.MERGE_CONFLICT_RULE
+	}
+	if !slices.Contains(domain.MergeRules, usernameRule) || !slices.Contains(domain.MergeRules, phoneRule) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown merge rule"})
+		return
+	}
+
+	// Call the use case to merge the users
+	mergedUser, err := c.UserUsecase.MergeUsers(ctx.Request.Context(), primaryID, duplicateIDs, usernameRule, phoneRule, actorOf(ctx))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "Phone or username already exists"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge users"})
+		return
+	}
+
+	// Return the merged user with a 200 OK status
+	ctx.JSON(http.StatusOK, mergedUser)
+}
\ No newline at end of file
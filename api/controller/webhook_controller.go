@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"errors"
+	"findApi/domain"
+	"findApi/usecase"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type WebhookController struct {
+	WebhookUsecase usecase.WebhooksUseCase
+}
+
+// CreateWebhook handles subscribing a new webhook to user events
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var webhook domain.Webhook
+	// Parse the request body to get the webhook details
+	if err := ctx.ShouldBindJSON(&webhook); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	// Validate the subscription
+	target, err := url.ParseRequestURI(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "A valid http or https URL is required"})
+		return
+	}
+	if len(webhook.Events) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "At least one event type is required"})
+		return
+	}
+	for _, event := range webhook.Events {
+		if !slices.Contains(domain.EventTypes, event) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type " + event})
+			return
+		}
+	}
+	if webhook.Secret == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Secret is required"})
+		return
+	}
+
+	// Call use case to insert the webhook
+	createdWebhook, err := c.WebhookUsecase.CreateWebhook(ctx.Request.Context(), &webhook)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	// Return the created webhook without its secret with a 201 Created status
+	createdWebhook.Secret = ""
+	ctx.JSON(http.StatusCreated, createdWebhook)
+}
+
+// FindWebhooks handles fetching all webhooks
+func (c *WebhookController) FindWebhooks(ctx *gin.Context) {
+	webhooks, err := c.WebhookUsecase.FindWebhooks(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+
+	// Return the list of webhooks with a 200 OK status
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook handles removing a webhook by id
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	err = c.WebhookUsecase.DeleteWebhook(ctx.Request.Context(), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	// Return a success message with a 200 OK status
+	ctx.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// FindDeliveries handles fetching the delivery log of a webhook by id
+func (c *WebhookController) FindDeliveries(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	deliveries, err := c.WebhookUsecase.FindDeliveries(ctx.Request.Context(), id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+		return
+	}
+
+	// Return the delivery log with a 200 OK status
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// FindDeadLetters handles fetching all events that could not be delivered
+func (c *WebhookController) FindDeadLetters(ctx *gin.Context) {
+	deadLetters, err := c.WebhookUsecase.FindDeadLetters(ctx.Request.Context())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead letters"})
+		return
+	}
+
+	// Return the dead-letter queue with a 200 OK status
+	ctx.JSON(http.StatusOK, deadLetters)
+}
+
+// RetryDeadLetter handles redelivering an event from the dead-letter queue by id
+func (c *WebhookController) RetryDeadLetter(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dead letter id"})
+		return
+	}
+
+	err = c.WebhookUsecase.RetryDeadLetter(ctx.Request.Context(), id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Dead letter or webhook not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry dead letter"})
+		return
+	}
+
+	// The event is redelivered in the background
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Dead letter queued for delivery"})
+}
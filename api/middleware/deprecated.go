@@ -0,0 +1,13 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// Deprecated marks the responses of a route as deprecated, pointing clients to
+// the route replacing it with a successor-version link
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", "true")
+		ctx.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+		ctx.Next()
+	}
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"findApi/internal/logging"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveParams are the path parameters holding personal data, masked in the log
+var sensitiveParams = map[string]bool{
+	"phone":    true,
+	"username": true,
+}
+
+// loggedQueryKeys are the query parameters whose values are logged, the
+// values of all others are masked
+var loggedQueryKeys = map[string]bool{
+	"sort": true, "group": true, "favorite": true, "facets": true, "since": true, "minScore": true,
+	"size": true, "days": true, "tz": true, "limit": true, "cursor": true, "type": true,
+	"bidirectional": true, "depth": true,
+}
+
+// Logger logs every request once it is handled, with its route, status and
+// latency. Personal data in the path and query string is masked.
+func Logger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", redactPath(ctx)),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.Int("bytes", max(ctx.Writer.Size(), 0)),
+		}
+		if query := redactQuery(ctx.Request.URL.Query()); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", ctx.Errors.String()))
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// Recovery answers 500 Internal Server Error to requests whose handler
+// panicked and logs the panic with its stack, leaving out the request headers
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, recovered any) {
+		slog.ErrorContext(ctx.Request.Context(), "Recovered from panic",
+			slog.String("route", ctx.FullPath()),
+			slog.String("error", fmt.Sprint(recovered)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// redactPath returns the requested path with the sensitive parameters masked
+func redactPath(ctx *gin.Context) string {
+	route := ctx.FullPath()
+	if route == "" {
+		return logging.Redact(ctx.Request.URL.Path)
+	}
+
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		if sensitiveParams[name] {
+			segments[i] = logging.Redacted
+		} else {
+			segments[i] = ctx.Param(name)
+		}
+	}
+	return logging.Redact(strings.Join(segments, "/"))
+}
+
+// redactQuery returns the query string with the values of all but the logged keys masked
+func redactQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(query))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			if !loggedQueryKeys[key] {
+				value = logging.Redacted
+			}
+			pairs = append(pairs, key+"="+value)
+		}
+	}
+	return logging.Redact(strings.Join(pairs, "&"))
+}
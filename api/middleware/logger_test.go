@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var got string
+	record := func(ctx *gin.Context) { got = redactPath(ctx) }
+	router := gin.New()
+	router.GET("/users/:id", record)
+	router.GET("/users/phone/:phone", record)
+	router.GET("/users/username/:username/photo/:size", record)
+	router.NoRoute(record)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/64b7f3a2c9e77a0012345678", "/users/64b7f3a2c9e77a0012345678"},
+		{"/users/phone/5551234567", "/users/phone/[redacted]"},
+		{"/users/phone/ada", "/users/phone/[redacted]"},
+		{"/users/username/ada/photo/small", "/users/username/[redacted]/photo/small"},
+		{"/unknown/5551234567", "/unknown/[redacted]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got = ""
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if got != tt.want {
+				t.Errorf("redactPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+		want  string
+	}{
+		{"empty", url.Values{}, ""},
+		{"logged keys", url.Values{"limit": {"10"}, "sort": {"username"}}, "limit=10&sort=username"},
+		{"other keys", url.Values{"q": {"ada"}, "tag": {"family", "work"}}, "q=[redacted]&tag=[redacted]&tag=[redacted]"},
+		{"personal data in a logged key", url.Values{"cursor": {"5551234567"}}, "cursor=[redacted]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactQuery(tt.query); got != tt.want {
+				t.Errorf("redactQuery(%v) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
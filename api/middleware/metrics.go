@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"findApi/internal/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the count and latency of every request under its route
+// template, so paths such as /users/phone/:phone are never labeled with the
+// phone itself
+func Metrics() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		metrics.ObserveHTTP(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), start)
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"findApi/domain"
+	"findApi/repository"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader identifies a client by one of the configured API keys
+const APIKeyHeader = "X-API-Key"
+
+// RateLimiter limits how fast clients make requests with token buckets. A
+// client sending a configured API key has a bucket per key with a larger
+// limit, any other client a bucket per IP address. Unknown API keys are
+// ignored, so making up keys does not get a client fresh buckets.
+type RateLimiter struct {
+	Store repository.RateLimitStore
+	// APIKeys are the known API keys
+	APIKeys map[string]bool
+	// APIKeyFactor multiplies the limits of clients with an API key
+	APIKeyFactor float64
+}
+
+// Limit returns a middleware taking a token from the bucket of the client for
+// the scope and answering 429 Too Many Requests with Retry-After once the
+// bucket is empty. Every scope has buckets of its own. When the store fails
+// the request is let through.
+func (l *RateLimiter) Limit(scope string, limit domain.RateLimit) gin.HandlerFunc {
+	return l.LimitCost(scope, limit, func(*gin.Context) float64 { return 1 })
+}
+
+// LimitCost is Limit for requests that differ in cost, taking as many tokens
+// as cost returns for the request. A request costing more than the client's
+// burst could never be let through and is answered with 413 Request Entity
+// Too Large instead.
+func (l *RateLimiter) LimitCost(scope string, limit domain.RateLimit, cost func(*gin.Context) float64) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !limit.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		client, clientLimit := "ip:"+ctx.ClientIP(), limit
+		if key := ctx.GetHeader(APIKeyHeader); key != "" && l.APIKeys[key] {
+			// Keep the key itself out of the store
+			sum := sha256.Sum256([]byte(key))
+			client, clientLimit = "key:"+hex.EncodeToString(sum[:8]), limit.Scale(l.APIKeyFactor)
+		}
+
+		tokens := cost(ctx)
+		if tokens > clientLimit.Burst {
+			ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request too large"})
+			return
+		}
+
+		allowed, retryAfter, err := l.Store.Take(ctx.Request.Context(), scope+"/"+client, clientLimit, tokens)
+		if err != nil {
+			slog.WarnContext(ctx.Request.Context(), "Failed to apply rate limit", "scope", scope, "error", err)
+			ctx.Next()
+			return
+		}
+		if !allowed {
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Started turns requests away with 503 Service Unavailable until started
+// reports true, so nothing reaches the database before its indexes are in
+// place and its data is backfilled, even when a client or load balancer
+// ignores the readiness check
+func Started(started func() bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !started() {
+			ctx.Header("Retry-After", "5")
+			ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is starting"})
+			return
+		}
+		ctx.Next()
+	}
+}
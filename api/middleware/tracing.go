@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"findApi/internal/tracing"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// controllerMethod picks the controller and method out of a handler name such
+// as findApi/api/controller.(*UserController).GetUserByPhone-fm
+var controllerMethod = regexp.MustCompile(`\(\*(\w+)\)\.(\w+)`)
+
+// Tracing traces every request in a server span, continuing the trace of the
+// caller from the W3C traceparent header. The span is named after the route
+// template and the requested path is left out, as it carries identifiers.
+func Tracing() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		route := ctx.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		spanCtx, span := tracing.Start(parent, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", ctx.Request.Method),
+				attribute.String("http.route", route),
+			),
+		)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+	}
+}
+
+// TraceHandler traces the controller method handling the request in a span of
+// its own, such as UserController.GetUserByPhone. It must come after the other
+// global middleware, so the span only covers the route and its handler.
+func TraceHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := "handler"
+		if match := controllerMethod.FindStringSubmatch(ctx.HandlerName()); match != nil {
+			name = match[1] + "." + match[2]
+		}
+
+		spanCtx, span := tracing.Start(ctx.Request.Context(), name)
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewEventRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env, subscriber usecase.EventSubscriber) {
+	repo := repository.NewOutboxRepository(db.Collection("outbox"), db.Collection("counters"), env)
+	usecase := usecase.NewEventsUseCase(repo)
+	controller := &controller.EventController{EventUsecase: usecase, Subscriber: subscriber}
+	r.GET("/users/events", controller.StreamEvents) // Stream the user events of the caller's address book over Server-Sent Events
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewGroupRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env) {
+	repo := repository.NewGroupRepository(db.Collection("groups"))
+	users := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	usecase := usecase.NewGroupsUseCase(repo, users)
+	controller := &controller.GroupController{GroupUsecase: usecase}
+	r.POST("/groups", controller.CreateGroup)                        // Create a new group
+	r.GET("/groups", controller.FindGroups)                          // Get all groups
+	r.GET("/groups/:id", controller.GetGroup)                        // Get a group by id
+	r.PUT("/groups/:id", controller.RenameGroup)                     // Rename a group
+	r.DELETE("/groups/:id", controller.DeleteGroup)                  // Delete a group, keeping its members
+	r.POST("/groups/:id/members", controller.AddMembers)             // Add users to a group
+	r.DELETE("/groups/:id/members/:userId", controller.RemoveMember) // Take a user out of a group
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NewHealthRoute(r *gin.Engine, health usecase.HealthUseCase) {
+	controller := &controller.HealthController{HealthUsecase: health}
+	r.GET("/healthz", controller.Live) // Report that the process is up
+	r.GET("/readyz", controller.Ready) // Report whether the server takes traffic, with the outcome of every check
+}
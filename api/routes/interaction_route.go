@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewInteractionRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env) {
+	repo := repository.NewInteractionRepository(db.Collection("interactions"), db.Collection("subject_keys"), env)
+	users := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	usecase := usecase.NewInteractionsUseCase(repo, users)
+	controller := &controller.InteractionController{InteractionUsecase: usecase}
+	r.POST("/users/:id/interactions", controller.AddInteraction)                     // Record an interaction with a user
+	r.GET("/users/:id/interactions", controller.FindInteractions)                    // Get a page of the interactions with a user
+	r.DELETE("/users/:id/interactions/:interactionId", controller.DeleteInteraction) // Remove an interaction with a user
+}
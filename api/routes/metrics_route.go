@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func NewMetricsRoute(r *gin.Engine) {
+	r.GET("/metrics", gin.WrapH(promhttp.Handler())) // Expose the metrics to Prometheus
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewPrivacyRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env, photos repository.BlobStore) {
+	users := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	interactions := repository.NewInteractionRepository(db.Collection("interactions"), db.Collection("subject_keys"), env)
+	relationships := repository.NewRelationshipRepository(db.Collection("relationships"), db.Collection("users"))
+	groups := repository.NewGroupRepository(db.Collection("groups"))
+	reminders := repository.NewReminderRepository(db.Collection("reminder_notifications"))
+	webhooks := repository.NewWebhookRepository(db.Collection("webhooks"), db.Collection("webhook_deliveries"), db.Collection("webhook_dead_letters"), db.Collection("webhook_queue"), env)
+	usecase := usecase.NewPrivacyUseCase(users, interactions, relationships, groups, reminders, webhooks, photos, env.BACKUP_RETENTION)
+	controller := &controller.PrivacyController{PrivacyUsecase: usecase}
+	r.POST("/privacy/export", controller.ExportSubject) // Export everything kept about a username or phone
+	r.POST("/privacy/erase", controller.EraseSubject)   // Erase everything kept about a username or phone
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewRelationshipRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env) {
+	repo := repository.NewRelationshipRepository(db.Collection("relationships"), db.Collection("users"))
+	users := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	usecase := usecase.NewRelationshipsUseCase(repo, users)
+	controller := &controller.RelationshipController{RelationshipUsecase: usecase}
+	r.POST("/users/:id/relationships", controller.AddRelationship)                      // Relate another user to a user
+	r.GET("/users/:id/relationships", controller.FindRelationships)                     // Get the relationships of a user
+	r.DELETE("/users/:id/relationships/:relationshipId", controller.DeleteRelationship) // Remove a relationship of a user
+	r.GET("/users/:id/related", controller.FindRelated)                                 // Follow relationships up to ?depth= edges away
+}
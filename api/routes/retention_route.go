@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewRetentionRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env, photos repository.BlobStore) {
+	repo := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	relationships := repository.NewRelationshipRepository(db.Collection("relationships"), db.Collection("users"))
+	interactions := repository.NewInteractionRepository(db.Collection("interactions"), db.Collection("subject_keys"), env)
+	users := usecase.NewUsersUseCase(repo, photos, relationships, interactions)
+	policies := repository.NewRetentionPolicyRepository(db.Collection("retention_policies"))
+	defaults := domain.RetentionPolicy{
+		StaleUsers: env.RETENTION_STALE_USERS,
+		Trash:      env.TRASH_RETENTION,
+		Revisions:  env.RETENTION_REVISIONS,
+	}
+	usecase := usecase.NewRetentionUseCase(repo, users, policies, defaults)
+	controller := &controller.RetentionController{RetentionUsecase: usecase}
+	r.GET("/retention/preview", controller.PreviewRetention)        // Report what the retention rules of the address book would remove now
+	r.GET("/retention/policy", controller.GetRetentionPolicy)       // Get the retention policy of the address book
+	r.PUT("/retention/policy", controller.SetRetentionPolicy)       // Give the address book a retention policy of its own
+	r.DELETE("/retention/policy", controller.DeleteRetentionPolicy) // Fall back to the default retention policy
+}
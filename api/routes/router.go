@@ -1,15 +1,34 @@
 package routes
 
 import (
+	"findApi/api/middleware"
 	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/repository"
+	"findApi/usecase"
+	"findApi/worker"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 
-func SetupRoutes(router *gin.Engine,db *mongo.Database,env *bootstrap.Env) {
+func SetupRoutes(router *gin.Engine,db *mongo.Database,env *bootstrap.Env,dispatcher *worker.WebhookDispatcher,bus *worker.EventBus,photos repository.BlobStore,health usecase.HealthUseCase,limiter *middleware.RateLimiter,started func() bool) {
 
-	NewUserRoute(router,db,env)
+	// Probes and scrapes come before the middleware, so they are not logged, traced or rate limited
+	NewHealthRoute(router,health)
+	NewMetricsRoute(router)
+
+	// Requests are turned away until the indexes and backfills run at startup are done
+	router.Use(middleware.Tracing(),middleware.RequestID(),middleware.Logger(),middleware.Recovery(),middleware.Metrics(),middleware.Started(started),limiter.Limit("default",domain.RateLimit{Rate: env.RATE_LIMIT_RPS, Burst: env.RATE_LIMIT_BURST}),middleware.TraceHandler())
+
+	NewUserRoute(router,db,env,photos,limiter)
+	NewWebhookRoute(router,db,env,dispatcher)
+	NewEventRoute(router,db,env,bus)
+	NewGroupRoute(router,db,env)
+	NewRelationshipRoute(router,db,env)
+	NewInteractionRoute(router,db,env)
+	NewPrivacyRoute(router,db,env,photos)
+	NewRetentionRoute(router,db,env,photos)
 
 }
\ No newline at end of file
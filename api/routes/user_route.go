@@ -2,7 +2,9 @@ package routes
 
 import (
 	"findApi/api/controller"
+	"findApi/api/middleware"
 	"findApi/bootstrap"
+	"findApi/domain"
 	"findApi/repository"
 	"findApi/usecase"
 
@@ -10,14 +12,50 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewUserRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env) {
-	repo := repository.NewUserRepository(db.Collection("users"),env)
-	usecase := usecase.NewUsersUseCase(repo)
+func NewUserRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env, photos repository.BlobStore, limiter *middleware.RateLimiter) {
+	repo := repository.NewUserRepository(db.Collection("users"),db.Collection("revisions"),db.Collection("outbox"),db.Collection("counters"),db.Collection("subject_keys"),env)
+	relationships := repository.NewRelationshipRepository(db.Collection("relationships"), db.Collection("users"))
+	interactions := repository.NewInteractionRepository(db.Collection("interactions"), db.Collection("subject_keys"), env)
+	usecase := usecase.NewUsersUseCase(repo, photos, relationships, interactions)
+	photoController := &controller.PhotoController{UserUsecase: usecase, Env: env}
+	reminderController := &controller.ReminderController{UserUsecase: usecase, Env: env}
+	// Lookups tell whether an identifier is in the book, so they are limited further
+	lookupLimit := limiter.Limit("lookup", domain.RateLimit{Rate: env.RATE_LIMIT_LOOKUP_RPS, Burst: env.RATE_LIMIT_LOOKUP_BURST})
+	// Batch lookups pay a token for every identifier looked up
+	batchLookup := domain.RateLimit{Rate: env.RATE_LIMIT_BATCH_LOOKUP_RPS, Burst: env.RATE_LIMIT_BATCH_LOOKUP_BURST}
+	batchLookupLimit := limiter.LimitCost("batchLookup", batchLookup, controller.LookupCost)
+	// Discovery tells as much about the book, so it draws from the same buckets
+	discoveryLimit := limiter.LimitCost("batchLookup", batchLookup, controller.DiscoveryCost)
 	controller := &controller.UserController{UserUsecase: usecase, Env: env}
 	r.POST("/users", controller.CreateUser)          // Create a new user
-	r.GET("/users/username/:username", controller.GetUserByUsername) // Get user by username
-	r.GET("/users/phone/:phone", controller.GetUserByPhone)         // Get user by phone
+	r.POST("/users/lookup", batchLookupLimit, controller.LookupUsers) // Get users by usernames and phones in the body
+	r.POST("/users/discover", discoveryLimit, controller.DiscoverContacts) // Tell which hashed phones are in the book
+	// The username or phone in the URL ends up in logs and browser history, so these lookups can be phased out
+	switch env.LEGACY_LOOKUP_ROUTES {
+	case domain.LegacyLookupDisabled:
+		// Not served, clients use POST /users/lookup
+	case domain.LegacyLookupDeprecated:
+		deprecated := middleware.Deprecated("/users/lookup")
+		r.GET("/users/username/:username", deprecated, lookupLimit, controller.GetUserByUsername) // Get user by username
+		r.GET("/users/phone/:phone", deprecated, lookupLimit, controller.GetUserByPhone)         // Get user by phone
+	default:
+		r.GET("/users/username/:username", lookupLimit, controller.GetUserByUsername) // Get user by username
+		r.GET("/users/phone/:phone", lookupLimit, controller.GetUserByPhone)         // Get user by phone
+	}
 	r.PUT("/users", controller.UpdateUser)        // Update user by username or phone
-	r.DELETE("/users", controller.DeleteUser)     // Delete user by username or phone
-	r.GET("/users", controller.FindAllUsers)      // Get all users
+	r.DELETE("/users", controller.DeleteUser)     // Move user to trash by username or phone
+	r.GET("/users", controller.FindAllUsers)      // Get all users, optionally filtered by group, tags and favorite flag and sorted
+	r.POST("/users/tags", controller.TagUsers)     // Change tags and favorite flag of several users
+	r.GET("/users/changes", controller.GetChanges) // Get user changes since a sync token
+	r.GET("/users/duplicates", controller.FindDuplicates) // Get pairs of likely duplicate users
+	r.POST("/users/merge", controller.MergeUsers)        // Fold duplicate users into a primary user
+	r.GET("/trash", controller.FindTrash)         // Get all users in the trash
+	r.POST("/users/:id/restore", controller.RestoreUser) // Restore user from the trash
+	r.GET("/users/:id/revisions", controller.FindRevisions) // Get prior versions of a user
+	r.POST("/users/:id/revisions/:rev/revert", controller.RevertUser) // Revert user to a prior version
+	r.PUT("/users/:id/photo", photoController.UploadPhoto)   // Set the photo of a user
+	r.GET("/users/:id/photo", photoController.GetPhoto)      // Get the photo of a user, or a thumbnail with ?size=
+	r.DELETE("/users/:id/photo", photoController.DeletePhoto) // Remove the photo of a user
+	r.GET("/users/:id/vcard", photoController.ExportVCard)   // Export a user as a vCard with its photo
+	r.GET("/reminders/upcoming", reminderController.UpcomingReminders) // Get birthdays and anniversaries in the next ?days=
 }
\ No newline at end of file
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"findApi/api/controller"
+	"findApi/bootstrap"
+	"findApi/repository"
+	"findApi/usecase"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func NewWebhookRoute(r *gin.Engine, db *mongo.Database, env *bootstrap.Env, deliverer usecase.WebhookDeliverer) {
+	repo := repository.NewWebhookRepository(db.Collection("webhooks"), db.Collection("webhook_deliveries"), db.Collection("webhook_dead_letters"), db.Collection("webhook_queue"), env)
+	usecase := usecase.NewWebhooksUseCase(repo, deliverer)
+	controller := &controller.WebhookController{WebhookUsecase: usecase}
+	r.POST("/webhooks", controller.CreateWebhook)                          // Subscribe a webhook to user events
+	r.GET("/webhooks", controller.FindWebhooks)                            // Get all webhooks
+	r.DELETE("/webhooks/:id", controller.DeleteWebhook)                    // Remove a webhook
+	r.GET("/webhooks/:id/deliveries", controller.FindDeliveries)           // Get the delivery log of a webhook
+	r.GET("/webhooks/dead-letters", controller.FindDeadLetters)            // Get events that could not be delivered
+	r.POST("/webhooks/dead-letters/:id/retry", controller.RetryDeadLetter) // Redeliver an event from the dead-letter queue
+}
@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -11,11 +12,109 @@ type Env struct{
 	PORT string `mapstructure:"PORT"`
 	DB_NAME string `mapstructure:"DB_NAME"`
 	SECRET_KEY string `mapstructure:"SECRET_KEY"`
+	LOG_LEVEL string `mapstructure:"LOG_LEVEL"`
+	TRACE_EXPORTER string `mapstructure:"TRACE_EXPORTER"`
+	TRACE_SAMPLE_RATIO float64 `mapstructure:"TRACE_SAMPLE_RATIO"`
+	TRUSTED_PROXIES string `mapstructure:"TRUSTED_PROXIES"`
+	RATE_LIMIT_STORE string `mapstructure:"RATE_LIMIT_STORE"`
+	RATE_LIMIT_RPS float64 `mapstructure:"RATE_LIMIT_RPS"`
+	RATE_LIMIT_BURST float64 `mapstructure:"RATE_LIMIT_BURST"`
+	RATE_LIMIT_LOOKUP_RPS float64 `mapstructure:"RATE_LIMIT_LOOKUP_RPS"`
+	RATE_LIMIT_LOOKUP_BURST float64 `mapstructure:"RATE_LIMIT_LOOKUP_BURST"`
+	RATE_LIMIT_BATCH_LOOKUP_RPS float64 `mapstructure:"RATE_LIMIT_BATCH_LOOKUP_RPS"`
+	RATE_LIMIT_BATCH_LOOKUP_BURST float64 `mapstructure:"RATE_LIMIT_BATCH_LOOKUP_BURST"`
+	RATE_LIMIT_API_KEYS string `mapstructure:"RATE_LIMIT_API_KEYS"`
+	LOOKUP_MAX_BATCH int `mapstructure:"LOOKUP_MAX_BATCH"`
+	LEGACY_LOOKUP_ROUTES string `mapstructure:"LEGACY_LOOKUP_ROUTES"`
+	PHONE_COUNTRY_CODE string `mapstructure:"PHONE_COUNTRY_CODE"`
+	RATE_LIMIT_API_KEY_FACTOR float64 `mapstructure:"RATE_LIMIT_API_KEY_FACTOR"`
+	SHUTDOWN_DELAY time.Duration `mapstructure:"SHUTDOWN_DELAY"`
+	SHUTDOWN_TIMEOUT time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	HEALTH_CHECK_TIMEOUT time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	HEALTH_STALL_TIMEOUT time.Duration `mapstructure:"HEALTH_STALL_TIMEOUT"`
+	DB_READ_TIMEOUT time.Duration `mapstructure:"DB_READ_TIMEOUT"`
+	DB_WRITE_TIMEOUT time.Duration `mapstructure:"DB_WRITE_TIMEOUT"`
+	DB_LIST_TIMEOUT time.Duration `mapstructure:"DB_LIST_TIMEOUT"`
+	TRASH_RETENTION time.Duration `mapstructure:"TRASH_RETENTION"`
+	RETENTION_STALE_USERS time.Duration `mapstructure:"RETENTION_STALE_USERS"`
+	RETENTION_REVISIONS time.Duration `mapstructure:"RETENTION_REVISIONS"`
+	RETENTION_DRY_RUN bool `mapstructure:"RETENTION_DRY_RUN"`
+	RETENTION_INTERVAL time.Duration `mapstructure:"RETENTION_INTERVAL"`
+	ERASURE_DEADLINE time.Duration `mapstructure:"ERASURE_DEADLINE"`
+	BACKUP_RETENTION time.Duration `mapstructure:"BACKUP_RETENTION"`
+	WEBHOOK_MAX_ATTEMPTS int `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WEBHOOK_RETRY_BACKOFF time.Duration `mapstructure:"WEBHOOK_RETRY_BACKOFF"`
+	WEBHOOK_TIMEOUT time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WEBHOOK_POLL_INTERVAL time.Duration `mapstructure:"WEBHOOK_POLL_INTERVAL"`
+	WEBHOOK_BATCH_SIZE int `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	OUTBOX_SINKS string `mapstructure:"OUTBOX_SINKS"`
+	OUTBOX_POLL_INTERVAL time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
+	OUTBOX_BATCH_SIZE int64 `mapstructure:"OUTBOX_BATCH_SIZE"`
+	DUPLICATE_MIN_SCORE float64 `mapstructure:"DUPLICATE_MIN_SCORE"`
+	MERGE_CONFLICT_RULE string `mapstructure:"MERGE_CONFLICT_RULE"`
+	PHOTO_STORE string `mapstructure:"PHOTO_STORE"`
+	PHOTO_DIR string `mapstructure:"PHOTO_DIR"`
+	PHOTO_MAX_BYTES int64 `mapstructure:"PHOTO_MAX_BYTES"`
+	REMINDER_NOTIFIERS string `mapstructure:"REMINDER_NOTIFIERS"`
+	REMINDER_TIMEZONE string `mapstructure:"REMINDER_TIMEZONE"`
+	REMINDER_LEAD_DAYS int `mapstructure:"REMINDER_LEAD_DAYS"`
+	REMINDER_INTERVAL time.Duration `mapstructure:"REMINDER_INTERVAL"`
+	REMINDER_WEBHOOK_URL string `mapstructure:"REMINDER_WEBHOOK_URL"`
+	REMINDER_WEBHOOK_SECRET string `mapstructure:"REMINDER_WEBHOOK_SECRET"`
+	REMINDER_MAIL_FROM string `mapstructure:"REMINDER_MAIL_FROM"`
+	REMINDER_MAIL_TO string `mapstructure:"REMINDER_MAIL_TO"`
 }
 
 func LoadEnv() *Env{
 	env := Env{}
 	viper.SetConfigFile(".env")
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("TRACE_EXPORTER", "none")
+	viper.SetDefault("TRACE_SAMPLE_RATIO", 1)
+	viper.SetDefault("TRUSTED_PROXIES", "")
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("RATE_LIMIT_RPS", 10)
+	viper.SetDefault("RATE_LIMIT_BURST", 20)
+	viper.SetDefault("RATE_LIMIT_LOOKUP_RPS", 0.2)
+	viper.SetDefault("RATE_LIMIT_LOOKUP_BURST", 5)
+	viper.SetDefault("RATE_LIMIT_BATCH_LOOKUP_RPS", 1)
+	viper.SetDefault("RATE_LIMIT_BATCH_LOOKUP_BURST", 500)
+	viper.SetDefault("RATE_LIMIT_API_KEYS", "")
+	viper.SetDefault("LOOKUP_MAX_BATCH", 500)
+	viper.SetDefault("LEGACY_LOOKUP_ROUTES", "enabled")
+	viper.SetDefault("PHONE_COUNTRY_CODE", "")
+	viper.SetDefault("RATE_LIMIT_API_KEY_FACTOR", 10)
+	viper.SetDefault("SHUTDOWN_DELAY", "5s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", "2s")
+	viper.SetDefault("HEALTH_STALL_TIMEOUT", "10m")
+	viper.SetDefault("DB_READ_TIMEOUT", "5s")
+	viper.SetDefault("DB_WRITE_TIMEOUT", "10s")
+	viper.SetDefault("DB_LIST_TIMEOUT", "10s")
+	viper.SetDefault("TRASH_RETENTION", "720h")
+	viper.SetDefault("RETENTION_STALE_USERS", 0)
+	viper.SetDefault("RETENTION_REVISIONS", 0)
+	viper.SetDefault("RETENTION_DRY_RUN", false)
+	viper.SetDefault("RETENTION_INTERVAL", "1h")
+	viper.SetDefault("ERASURE_DEADLINE", "720h")
+	viper.SetDefault("BACKUP_RETENTION", "720h")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 5)
+	viper.SetDefault("WEBHOOK_RETRY_BACKOFF", "1s")
+	viper.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", "1s")
+	viper.SetDefault("WEBHOOK_BATCH_SIZE", 50)
+	viper.SetDefault("OUTBOX_SINKS", "webhook,bus")
+	viper.SetDefault("OUTBOX_POLL_INTERVAL", "1s")
+	viper.SetDefault("OUTBOX_BATCH_SIZE", 100)
+	viper.SetDefault("DUPLICATE_MIN_SCORE", 0.6)
+	viper.SetDefault("MERGE_CONFLICT_RULE", "primary")
+	viper.SetDefault("PHOTO_STORE", "local")
+	viper.SetDefault("PHOTO_DIR", "photos")
+	viper.SetDefault("PHOTO_MAX_BYTES", 5<<20)
+	viper.SetDefault("REMINDER_NOTIFIERS", "log")
+	viper.SetDefault("REMINDER_TIMEZONE", "UTC")
+	viper.SetDefault("REMINDER_LEAD_DAYS", 0)
+	viper.SetDefault("REMINDER_INTERVAL", "1h")
 
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -0,0 +1,31 @@
+package bootstrap
+
+import "sync/atomic"
+
+// Lifecycle tracks how far the application has come starting up. It takes
+// traffic once the indexes are in place and the background workers run, and
+// stops taking traffic when it starts shutting down.
+type Lifecycle struct {
+	indexed atomic.Bool
+	ready   atomic.Bool
+}
+
+// SetIndexed records that the database indexes are in place
+func (l *Lifecycle) SetIndexed() {
+	l.indexed.Store(true)
+}
+
+// Indexed reports whether the database indexes are in place
+func (l *Lifecycle) Indexed() bool {
+	return l.indexed.Load()
+}
+
+// SetReady marks the application as ready or not ready to take traffic
+func (l *Lifecycle) SetReady(ready bool) {
+	l.ready.Store(ready)
+}
+
+// Ready reports whether the application is ready to take traffic
+func (l *Lifecycle) Ready() bool {
+	return l.ready.Load()
+}
@@ -2,36 +2,276 @@ package main
 
 import (
 	"context"
+	"errors"
+	"findApi/api/middleware"
 	"findApi/api/routes"
 	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"findApi/internal/logging"
+	"findApi/internal/tracing"
+	"findApi/repository"
 	"findApi/repository/db"
+	"findApi/usecase"
+	"findApi/worker"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 func main() {
 	env := bootstrap.LoadEnv()
-	router := gin.Default()
+	logging.Setup(env.LOG_LEVEL)
+	stopTracing, err := tracing.Setup(context.Background(), env.TRACE_EXPORTER, env.TRACE_SAMPLE_RATIO)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	router := gin.New()
+	// The client IP is only taken from X-Forwarded-For when set by a trusted proxy
+	if err := router.SetTrustedProxies(splitList(env.TRUSTED_PROXIES)); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+	// Erased users stay in backups until these expire, so backups may not
+	// outlive the time an erasure is allowed to take
+	if env.BACKUP_RETENTION <= 0 || env.BACKUP_RETENTION > env.ERASURE_DEADLINE {
+		log.Fatalf("BACKUP_RETENTION must be positive and within ERASURE_DEADLINE, got %s for %s", env.BACKUP_RETENTION, env.ERASURE_DEADLINE)
+	}
 	client := db.NewMongoClient(env)
-	defer client.Disconnect(context.TODO())
 
 	db := client.Database(env.DB_NAME)
 
+	photos, err := repository.NewBlobStore(db, env)
+	if err != nil {
+		log.Fatalf("Failed to open photo store: %v", err)
+	}
+
+	// Run the background workers until shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	// Deliver webhooks in the background
+	dispatcher := worker.NewWebhookDispatcher(repository.NewWebhookRepository(db.Collection("webhooks"), db.Collection("webhook_deliveries"), db.Collection("webhook_dead_letters"), db.Collection("webhook_queue"), env), env)
+
+	// Publish outbox entries to the configured sinks in the background
+	bus := worker.NewEventBus()
+	outbox := &worker.OutboxDispatcher{
+		Outbox:    repository.NewOutboxRepository(db.Collection("outbox"), db.Collection("counters"), env),
+		Sinks:     newOutboxSinks(env.OUTBOX_SINKS, dispatcher, bus),
+		Interval:  env.OUTBOX_POLL_INTERVAL,
+		BatchSize: env.OUTBOX_BATCH_SIZE,
+	}
+
+	// Apply the retention rules in the background
+	relationships := repository.NewRelationshipRepository(db.Collection("relationships"), db.Collection("users"))
+	interactions := repository.NewInteractionRepository(db.Collection("interactions"), db.Collection("subject_keys"), env)
+	userRepo := repository.NewUserRepository(db.Collection("users"), db.Collection("revisions"), db.Collection("outbox"), db.Collection("counters"), db.Collection("subject_keys"), env)
+	users := usecase.NewUsersUseCase(userRepo, photos, relationships, interactions)
+	// Address books without a retention policy of their own are kept under the configured one
+	policies := repository.NewRetentionPolicyRepository(db.Collection("retention_policies"))
+	defaults := domain.RetentionPolicy{
+		StaleUsers: env.RETENTION_STALE_USERS,
+		Trash:      env.TRASH_RETENTION,
+		Revisions:  env.RETENTION_REVISIONS,
+	}
+	retention := &worker.RetentionJob{
+		RetentionUsecase: usecase.NewRetentionUseCase(userRepo, users, policies, defaults),
+		DryRun:           env.RETENTION_DRY_RUN,
+		Interval:         env.RETENTION_INTERVAL,
+	}
+
+	// Send birthday and anniversary reminders in the background
+	location, err := time.LoadLocation(env.REMINDER_TIMEZONE)
+	if err != nil {
+		log.Fatalf("Invalid reminder time zone: %v", err)
+	}
+	scheduler := &worker.ReminderScheduler{
+		UserUsecase: users,
+		Reminders:   repository.NewReminderRepository(db.Collection("reminder_notifications")),
+		Notifiers:   newNotifiers(env),
+		Location:    location,
+		LeadDays:    env.REMINDER_LEAD_DAYS,
+		Interval:    env.REMINDER_INTERVAL,
+	}
+
+	// The readiness check covers the database, the keys and every background worker
+	lifecycle := &bootstrap.Lifecycle{}
+	health := usecase.NewHealthUseCase([]usecase.HealthProbe{
+		{Name: "server", Check: func(ctx context.Context) error {
+			if !lifecycle.Ready() {
+				return errors.New("not taking traffic")
+			}
+			return nil
+		}},
+		{Name: "mongo", Check: func(ctx context.Context) error {
+			return client.Ping(ctx, readpref.Primary())
+		}},
+		{Name: "indexes", Check: func(ctx context.Context) error {
+			if !lifecycle.Indexed() {
+				return errors.New("indexes are being created")
+			}
+			return nil
+		}},
+		{Name: "keys", Check: func(ctx context.Context) error {
+			return checkSecretKey(env.SECRET_KEY)
+		}},
+		{Name: "webhooks", Check: func(ctx context.Context) error {
+			return dispatcher.Check()
+		}},
+		{Name: "outbox", Check: func(ctx context.Context) error {
+			return outbox.Heartbeat.Check(outbox.Interval + env.HEALTH_STALL_TIMEOUT)
+		}},
+		{Name: "retention", Check: func(ctx context.Context) error {
+			return retention.Heartbeat.Check(retention.Interval + env.HEALTH_STALL_TIMEOUT)
+		}},
+		{Name: "reminders", Check: func(ctx context.Context) error {
+			return scheduler.Heartbeat.Check(scheduler.Interval + env.HEALTH_STALL_TIMEOUT)
+		}},
+	}, env.HEALTH_CHECK_TIMEOUT)
+	// Limit how fast clients make requests
+	rateLimits, err := repository.NewRateLimitStore(db, env)
+	if err != nil {
+		log.Fatalf("Failed to open rate limit store: %v", err)
+	}
+	limiter := &middleware.RateLimiter{
+		Store:        rateLimits,
+		APIKeys:      make(map[string]bool),
+		APIKeyFactor: env.RATE_LIMIT_API_KEY_FACTOR,
+	}
+	for _, key := range splitList(env.RATE_LIMIT_API_KEYS) {
+		limiter.APIKeys[key] = true
+	}
+	routes.SetupRoutes(router, db, env, dispatcher, bus, photos, health, limiter, lifecycle.Indexed)
+
+	server := &http.Server{Addr: ":" + env.PORT, Handler: router}
+	// Event streams never finish on their own, end them so they do not hold up the drain
+	server.RegisterOnShutdown(bus.Close)
+
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Create the indexes and backfill while the server already answers the
+	// health checks, reporting not ready and turning every other request away
+	// until they are done and the workers run
+
 	// Create indexes for the users collection
 	if err := createUserIndexes(db); err != nil {
 		log.Fatalf("Failed to create indexes: %v", err)
 	}
+	if err := backfillUserTimestamps(db); err != nil {
+		log.Fatalf("Failed to backfill timestamps: %v", err)
+	}
+	if err := backfillPhoneDiscovery(userRepo); err != nil {
+		log.Fatalf("Failed to backfill phone discovery: %v", err)
+	}
+	if err := createRevisionIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+
+	if err := createWebhookIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createOutboxIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createGroupIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createReminderIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createRelationshipIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createInteractionIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+	if err := createRateLimitIndexes(db); err != nil {
+		log.Fatalf("Failed to create indexes: %v", err)
+	}
+
+	lifecycle.SetIndexed()
 
-	routes.SetupRoutes(router, db, env)
-	router.Run(":" + env.PORT)
+	runWorker(dispatcher.Run)
+	runWorker(outbox.Run)
+	runWorker(retention.Run)
+	runWorker(scheduler.Run)
+	lifecycle.SetReady(true)
+
+	select {
+	case err := <-serverErr:
+		slog.Error("Server stopped", "error", err)
+	case <-signals.Done():
+	}
+	// A second signal stops the process right away
+	stopSignals()
+
+	shutdown(server, lifecycle, stopWorkers, &workers, client, stopTracing, env)
+}
+
+// shutdown stops the application in order: readiness is turned off first so
+// the load balancer stops sending traffic, then the server drains in-flight
+// requests, then the background workers finish their current pass, the Mongo
+// client disconnects and last the remaining spans are flushed. Everything
+// after the delay shares SHUTDOWN_TIMEOUT.
+func shutdown(server *http.Server, lifecycle *bootstrap.Lifecycle, stopWorkers context.CancelFunc, workers *sync.WaitGroup, client *mongo.Client, stopTracing func(context.Context) error, env *bootstrap.Env) {
+	slog.Info("Shutting down")
+	lifecycle.SetReady(false)
+	time.Sleep(env.SHUTDOWN_DELAY)
+
+	ctx, cancel := context.WithTimeout(context.Background(), env.SHUTDOWN_TIMEOUT)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Failed to drain requests", "error", err)
+	}
+
+	stopWorkers()
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Background workers did not stop in time")
+	}
+
+	if err := client.Disconnect(ctx); err != nil {
+		slog.Error("Failed to disconnect from MongoDB", "error", err)
+	}
+	if err := stopTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	slog.Info("Shut down")
 }
 
-// createUserIndexes creates indexes for the users collection on both phone and username fields
+// createUserIndexes creates indexes for the users collection on the phone and username fields, the change sequence, group membership, tags, the favorite flag, the last contact, the creation, the last change, the address book and the phone discovery fields
 func createUserIndexes(db *mongo.Database) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -47,9 +287,270 @@ func createUserIndexes(db *mongo.Database) error {
 			Keys: bson.D{{Key: "username", Value: 1}},
 			Options: options.Index().SetUnique(true).SetSparse(true),
 		},
+		{
+			Keys: bson.D{{Key: "changeSeq", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "groupIds", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "tags", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "favorite", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "lastContactedAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "createdAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "updatedAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "addressBook", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "discovery.index", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "discovery.bucket", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	// Create indexes
 	_, err := usersCollection.Indexes().CreateMany(ctx, indexes)
 	return err
-}
\ No newline at end of file
+}
+
+// backfillUserTimestamps sets the creation and last change time of users from before they were
+// recorded to the creation time kept in their id
+func backfillUserTimestamps(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	created := bson.M{"$toDate": "$_id"}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"createdAt": bson.M{"$exists": false}},
+		bson.M{"updatedAt": bson.M{"$exists": false}},
+	}}
+	_, err := db.Collection("users").UpdateMany(ctx, filter, mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"createdAt": bson.M{"$ifNull": bson.A{"$createdAt", created}},
+		"updatedAt": bson.M{"$ifNull": bson.A{"$updatedAt", created}},
+	}}}})
+	return err
+}
+
+// backfillPhoneDiscovery makes the phones of users from before contact discovery discoverable
+func backfillPhoneDiscovery(users repository.UsersRepo) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	indexed, err := users.IndexPhones(ctx)
+	if indexed > 0 {
+		slog.Info("Indexed phones for discovery", "users", indexed)
+	}
+	return err
+}
+
+// createRevisionIndexes creates a unique index on user id and revision number for the revisions collection and an index on when they were saved
+func createRevisionIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("revisions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "rev", Value: -1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "savedAt", Value: 1}},
+		},
+	})
+	return err
+}
+
+// createWebhookIndexes creates indexes for looking up webhooks by event type, deliveries by webhook and due deliveries in the queue
+func createWebhookIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := db.Collection("webhooks").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "events", Value: 1}},
+	}); err != nil {
+		return err
+	}
+
+	if _, err := db.Collection("webhook_deliveries").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "webhookId", Value: 1}, {Key: "attemptedAt", Value: -1}},
+	}); err != nil {
+		return err
+	}
+
+	// An event is queued once per webhook, and due deliveries are found by time
+	_, err := db.Collection("webhook_queue").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "webhookId", Value: 1}, {Key: "event.id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "nextAttemptAt", Value: 1}, {Key: "leasedUntil", Value: 1}},
+		},
+	})
+	return err
+}
+
+// newOutboxSinks builds the outbox sinks from a comma separated list of names
+func newOutboxSinks(names string, dispatcher *worker.WebhookDispatcher, bus *worker.EventBus) []worker.EventSink {
+	var sinks []worker.EventSink
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "webhook":
+			sinks = append(sinks, dispatcher)
+		case "stdout":
+			sinks = append(sinks, worker.NewStdoutSink())
+		case "bus":
+			sinks = append(sinks, bus)
+		case "":
+		default:
+			log.Fatalf("Unknown outbox sink: %s", name)
+		}
+	}
+	return sinks
+}
+
+// newNotifiers builds the reminder notifiers from the comma separated list in REMINDER_NOTIFIERS
+func newNotifiers(env *bootstrap.Env) []worker.Notifier {
+	var notifiers []worker.Notifier
+	for _, name := range strings.Split(env.REMINDER_NOTIFIERS, ",") {
+		switch strings.TrimSpace(name) {
+		case "log":
+			notifiers = append(notifiers, worker.LogNotifier{})
+		case "webhook":
+			notifiers = append(notifiers, worker.NewWebhookNotifier(env.REMINDER_WEBHOOK_URL, env.REMINDER_WEBHOOK_SECRET, env.WEBHOOK_TIMEOUT))
+		case "mail":
+			notifiers = append(notifiers, worker.NewMailNotifier(env.REMINDER_MAIL_FROM, env.REMINDER_MAIL_TO))
+		case "":
+		default:
+			log.Fatalf("Unknown reminder notifier: %s", name)
+		}
+	}
+	return notifiers
+}
+
+// createOutboxIndexes creates indexes for reading pending entries, replaying events by sequence, also within an address book, and expiring published entries after a week
+func createOutboxIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("outbox").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "publishedAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+		{
+			Keys: bson.D{{Key: "event.seq", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "event.addressBook", Value: 1}, {Key: "event.seq", Value: 1}},
+		},
+	})
+	return err
+}
+
+// createGroupIndexes creates a unique index on the name of groups
+func createGroupIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("groups").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// createReminderIndexes expires the record of sent reminders once their date can no longer come up again
+func createReminderIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("reminder_notifications").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "notifiedAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(400 * 24 * 60 * 60),
+	})
+	return err
+}
+
+// createRelationshipIndexes creates a unique index on the edges between two users and an index on their targets
+func createRelationshipIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("relationships").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "from", Value: 1}, {Key: "type", Value: 1}, {Key: "to", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "to", Value: 1}},
+		},
+	})
+	return err
+}
+
+// createInteractionIndexes creates an index for reading the timeline of a user newest first
+func createInteractionIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("interactions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "at", Value: -1}, {Key: "_id", Value: -1}},
+	})
+	return err
+}
+
+// checkSecretKey checks that the secret key can encrypt and decrypt, as every
+// username, phone and subject key depends on it
+func checkSecretKey(secret string) error {
+	const probe = "readiness"
+	encrypted, err := encryptutil.EncryptECB(probe, []byte(secret))
+	if err != nil {
+		return err
+	}
+	decrypted, err := encryptutil.DecryptECB(encrypted, []byte(secret))
+	if err != nil {
+		return err
+	}
+	if decrypted != probe {
+		return errors.New("secret key does not decrypt what it encrypts")
+	}
+	return nil
+}
+
+// createRateLimitIndexes expires the rate limit buckets kept in MongoDB once they would have filled up again
+func createRateLimitIndexes(db *mongo.Database) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("rate_limits").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// splitList returns the non-empty entries of a comma separated list
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
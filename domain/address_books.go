@@ -0,0 +1,14 @@
+package domain
+
+// DefaultAddressBook holds the users created without naming an address book,
+// and every user from before there were address books
+const DefaultAddressBook = "default"
+
+// AddressBookOf returns the address book with the name, or the default one
+// for an empty name
+func AddressBookOf(name string) string {
+	if name == "" {
+		return DefaultAddressBook
+	}
+	return name
+}
@@ -0,0 +1,35 @@
+package domain
+
+// DiscoveryBucketLength is how many leading hex digits of a phone hash make
+// up its bucket. Asking for a bucket instead of a hash hides the number among
+// the roughly 10^4 numbers of a country sharing the prefix.
+const DiscoveryBucketLength = 5
+
+// DiscoveryReq asks which phone numbers are in the book without sending the
+// numbers. Every number is normalized to E.164 and hashed with SHA-256, then
+// sent either as the whole hex-encoded hash or, for k-anonymity, as its
+// first DiscoveryBucketLength hex digits.
+//
+// The hashes are unsalted and there are few enough phone numbers to hash all
+// of them, so a hash hides a number from logs but not from someone set on
+// reversing it. What stops enumerating the book is the rate limit: every hash
+// and prefix costs a token shared with lookups. A prefix reveals the hashes
+// of all numbers in its bucket, so walking every bucket reveals the book,
+// though only at the pace of the rate limit.
+type DiscoveryReq struct {
+	Hashes   []string `json:"hashes"`
+	Prefixes []string `json:"prefixes"`
+}
+
+// Size is the number of hashes and prefixes asked for
+func (r DiscoveryReq) Size() int {
+	return len(r.Hashes) + len(r.Prefixes)
+}
+
+// DiscoveryResult lists the hashes of the request that are in the book, and
+// for every prefix the hashes of all numbers in the book within its bucket,
+// which the client matches against its contacts itself
+type DiscoveryResult struct {
+	Matches []string            `json:"matches"`
+	Buckets map[string][]string `json:"buckets"`
+}
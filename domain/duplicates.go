@@ -0,0 +1,32 @@
+package domain
+
+// Merge conflict rules deciding which value a merged user keeps for a field
+const (
+	// MergeKeepPrimary keeps the primary's value, falling back to the first duplicate that has one
+	MergeKeepPrimary = "primary"
+	// MergeKeepDuplicate takes the first duplicate's value, falling back to the primary's
+	MergeKeepDuplicate = "duplicate"
+	// MergeKeepNewest takes the value of the most recently changed user that has one
+	MergeKeepNewest = "newest"
+)
+
+// MergeRules lists every merge conflict rule
+var MergeRules = []string{MergeKeepPrimary, MergeKeepDuplicate, MergeKeepNewest}
+
+// DuplicateCandidate is a pair of users that likely describe the same person
+type DuplicateCandidate struct {
+	First   *User    `json:"first"`
+	Second  *User    `json:"second"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
+
+// MergeReq asks to fold the duplicates into the primary user
+type MergeReq struct {
+	PrimaryID    string   `json:"primaryId"`
+	DuplicateIDs []string `json:"duplicateIds"`
+	Rules        struct {
+		Username string `json:"username"`
+		Phone    string `json:"phone"`
+	} `json:"rules"`
+}
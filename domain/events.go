@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User lifecycle event types
+const (
+	EventUserCreated  = "user.created"
+	EventUserUpdated  = "user.updated"
+	EventUserDeleted  = "user.deleted"
+	EventUserRestored = "user.restored"
+)
+
+// EventTypes lists every user lifecycle event type
+var EventTypes = []string{EventUserCreated, EventUserUpdated, EventUserDeleted, EventUserRestored}
+
+// Event describes a change to a user. Seq increases by one with every
+// committed event and is used to resume streams. AddressBook is the address
+// book of the user, so streams only carry the events of their own book.
+type Event struct {
+	ID          string    `json:"id" bson:"id"`
+	Seq         int64     `json:"seq" bson:"seq"`
+	Type        string    `json:"type" bson:"type"`
+	AddressBook string    `json:"addressBook" bson:"addressBook"`
+	OccurredAt  time.Time `json:"occurredAt" bson:"occurredAt"`
+	User        *User     `json:"user" bson:"user"`
+}
+
+// NewEvent creates an event of the given type about a user
+func NewEvent(eventType string, user *User) *Event {
+	return &Event{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        eventType,
+		AddressBook: AddressBookOf(user.AddressBook),
+		OccurredAt:  time.Now().UTC(),
+		User:        user,
+	}
+}
+
+// OutboxEntry is an event waiting to be published. It is written in the same
+// transaction as the change it describes.
+type OutboxEntry struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Event       Event              `json:"event" bson:"event"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	PublishedAt *time.Time         `json:"publishedAt,omitempty" bson:"publishedAt,omitempty"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group is a named set of users. Membership is kept on the users as GroupIDs.
+type Group struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	MemberCount int64              `json:"memberCount" bson:"-"`
+}
+
+// MembersReq lists the users to add to a group
+type MembersReq struct {
+	UserIDs []string `json:"userIds"`
+}
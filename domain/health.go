@@ -0,0 +1,23 @@
+package domain
+
+// Outcomes of a health check
+const (
+	HealthOK      = "ok"
+	HealthFailing = "failing"
+)
+
+// HealthCheck is the outcome of checking one dependency the application needs
+// to take traffic
+type HealthCheck struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// HealthReport is the outcome of the readiness checks. The application is
+// ready when every check is ok.
+type HealthReport struct {
+	Ready  bool           `json:"ready"`
+	Checks []*HealthCheck `json:"checks"`
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of interactions with a user
+const (
+	InteractionCall    = "call"
+	InteractionMeeting = "meeting"
+	InteractionEmail   = "email"
+)
+
+// InteractionTypes lists every kind of interaction
+var InteractionTypes = []string{InteractionCall, InteractionMeeting, InteractionEmail}
+
+// Interaction is an entry in the timeline of contacts with a user
+type Interaction struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Type      string             `json:"type" bson:"type"`
+	At        time.Time          `json:"at" bson:"at"`
+	Notes     string             `json:"notes,omitempty" bson:"notes,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// InteractionPage is one page of a user's timeline, newest first. NextCursor
+// is empty on the last page.
+type InteractionPage struct {
+	Interactions []*Interaction `json:"interactions"`
+	NextCursor   string         `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,33 @@
+package domain
+
+// Modes of the lookup routes taking the username or phone in the path
+const (
+	// LegacyLookupEnabled serves the routes as before
+	LegacyLookupEnabled = "enabled"
+	// LegacyLookupDeprecated serves the routes, marking the responses as deprecated
+	LegacyLookupDeprecated = "deprecated"
+	// LegacyLookupDisabled does not serve the routes
+	LegacyLookupDisabled = "disabled"
+)
+
+// LookupReq asks for the users with any of the usernames or phone numbers.
+// The identifiers travel in the body, so they stay out of URLs and the logs
+// of proxies on the way. A request holds at most LOOKUP_MAX_BATCH of them,
+// and each one costs a token of the batch lookup rate limit, so batching
+// saves round trips without speeding up enumerating the book.
+type LookupReq struct {
+	Usernames []string `json:"usernames"`
+	Phones    []string `json:"phones"`
+}
+
+// Size is the number of identifiers looked up
+func (r LookupReq) Size() int {
+	return len(r.Usernames) + len(r.Phones)
+}
+
+// LookupResult lists the users found by a lookup together with the
+// identifiers that matched none
+type LookupResult struct {
+	Users    []*User   `json:"users"`
+	NotFound LookupReq `json:"notFound"`
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// PhotoSizes are the edge lengths, in pixels, of the square boxes every
+// photo is scaled down into as thumbnails
+var PhotoSizes = []int{64, 128, 256}
+
+// Photo describes the photo of a user. The image and its thumbnails are kept
+// in a blob store under the user and photo id.
+type Photo struct {
+	ID          string    `json:"id" bson:"id"`
+	ContentType string    `json:"contentType" bson:"contentType"`
+	Size        int64     `json:"size" bson:"size"`
+	Width       int       `json:"width" bson:"width"`
+	Height      int       `json:"height" bson:"height"`
+	UpdatedAt   time.Time `json:"updatedAt" bson:"updatedAt"`
+}
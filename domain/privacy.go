@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SubjectReq identifies the data subject of an access or erasure request by
+// username, phone or both
+type SubjectReq struct {
+	Username string `json:"username"`
+	Phone    string `json:"phone"`
+}
+
+// SubjectRecord is everything kept about one user matching a data subject
+type SubjectRecord struct {
+	User          *User           `json:"user"`
+	Revisions     []*Revision     `json:"revisions"`
+	Interactions  []*Interaction  `json:"interactions"`
+	Relationships []*Relationship `json:"relationships"`
+	Groups        []*Group        `json:"groups"`
+	Photo         []byte          `json:"photo,omitempty"`
+}
+
+// SubjectExport is the machine-readable bundle answering an access request.
+// A subject can match several users, such as a trashed user and a newer one
+// with the same phone.
+type SubjectExport struct {
+	ExportedAt time.Time        `json:"exportedAt"`
+	Subject    SubjectReq       `json:"subject"`
+	Records    []*SubjectRecord `json:"records"`
+}
+
+// ErasureReport lists the users erased for an erasure request. The erasure
+// completes once the last backup taken before it has expired.
+type ErasureReport struct {
+	ErasedAt    time.Time            `json:"erasedAt"`
+	CompletesAt time.Time            `json:"completesAt"`
+	UserIDs     []primitive.ObjectID `json:"userIds"`
+}
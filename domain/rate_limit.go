@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// RateLimit is a token bucket holding up to Burst requests and refilling at
+// Rate requests per second. A zero rate disables the limit.
+type RateLimit struct {
+	Rate  float64
+	Burst float64
+}
+
+// Scale returns the limit multiplied by the factor
+func (l RateLimit) Scale(factor float64) RateLimit {
+	return RateLimit{Rate: l.Rate * factor, Burst: l.Burst * factor}
+}
+
+// Enabled reports whether the limit applies
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0 && l.Burst >= 1
+}
+
+// RefillTime is how long an empty bucket takes to fill up again, after which
+// an idle bucket can be forgotten
+func (l RateLimit) RefillTime() time.Duration {
+	return time.Duration(l.Burst / l.Rate * float64(time.Second))
+}
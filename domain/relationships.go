@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Relationship types. An edge of type manager from A to B reads "B is the
+// manager of A".
+const (
+	RelationSpouse    = "spouse"
+	RelationManager   = "manager"
+	RelationReport    = "report"
+	RelationAssistant = "assistant"
+	RelationPrincipal = "principal"
+	RelationChild     = "child"
+	RelationParent    = "parent"
+)
+
+// RelationInverses maps every relationship type to the type of the edge
+// pointing back, which a bidirectional relationship also records
+var RelationInverses = map[string]string{
+	RelationSpouse:    RelationSpouse,
+	RelationManager:   RelationReport,
+	RelationReport:    RelationManager,
+	RelationAssistant: RelationPrincipal,
+	RelationPrincipal: RelationAssistant,
+	RelationChild:     RelationParent,
+	RelationParent:    RelationChild,
+}
+
+// Relationship is a typed edge from one user to another
+type Relationship struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	From      primitive.ObjectID `json:"from" bson:"from"`
+	To        primitive.ObjectID `json:"to" bson:"to"`
+	Type      string             `json:"type" bson:"type"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// RelationshipReq records that the user To is related to a user
+type RelationshipReq struct {
+	To            string `json:"to"`
+	Type          string `json:"type"`
+	Bidirectional bool   `json:"bidirectional"`
+}
+
+// RelatedUser is a user reached by following relationships, Depth edges away
+// from where the traversal started
+type RelatedUser struct {
+	UserID primitive.ObjectID `json:"userId"`
+	Type   string             `json:"type"`
+	Of     primitive.ObjectID `json:"of"`
+	Depth  int                `json:"depth"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+// Kinds of yearly dates a reminder is raised for
+const (
+	ReminderBirthday    = "birthday"
+	ReminderAnniversary = "anniversary"
+)
+
+// Date is a calendar date whose year may be unknown, as is common for birthdays
+type Date struct {
+	Year  int `json:"year,omitempty" bson:"year,omitempty"`
+	Month int `json:"month" bson:"month"`
+	Day   int `json:"day" bson:"day"`
+}
+
+// Reminder is an upcoming birthday or anniversary of a user
+type Reminder struct {
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	Kind      string `json:"kind"`
+	Date      string `json:"date"`
+	DaysUntil int    `json:"daysUntil"`
+	Years     int    `json:"years,omitempty"`
+}
@@ -0,0 +1,84 @@
+package domain
+
+import "time"
+
+// Retention rules
+const (
+	// RetentionStaleUsers moves users that have not changed for a while to the trash
+	RetentionStaleUsers = "staleUsers"
+	// RetentionTrash permanently removes users that have been in the trash for a while
+	RetentionTrash = "trash"
+	// RetentionRevisions removes old revisions, which serve as the audit trail of users
+	RetentionRevisions = "revisions"
+)
+
+// RetentionPolicy sets how long the data of an address book is kept under
+// each retention rule. A zero duration disables the rule. The configured
+// default policy has no address book and covers every address book without
+// a policy of its own.
+type RetentionPolicy struct {
+	AddressBook string        `bson:"_id"`
+	StaleUsers  time.Duration `bson:"staleUsers"`
+	Trash       time.Duration `bson:"trash"`
+	Revisions   time.Duration `bson:"revisions"`
+}
+
+// Days returns the policy in days, the form in which the API shows it
+func (p RetentionPolicy) Days() RetentionDays {
+	const day = 24 * time.Hour
+	return RetentionDays{
+		AddressBook:    p.AddressBook,
+		Default:        p.AddressBook == "",
+		StaleUsersDays: float64(p.StaleUsers) / float64(day),
+		TrashDays:      float64(p.Trash) / float64(day),
+		RevisionsDays:  float64(p.Revisions) / float64(day),
+	}
+}
+
+// RetentionDays is a retention policy in days, the form in which the API
+// takes and shows policies. A rule of 0 days is disabled. Default marks the
+// configured policy shown for an address book without one of its own.
+type RetentionDays struct {
+	AddressBook    string  `json:"addressBook"`
+	Default        bool    `json:"default"`
+	StaleUsersDays float64 `json:"staleUsersDays"`
+	TrashDays      float64 `json:"trashDays"`
+	RevisionsDays  float64 `json:"revisionsDays"`
+}
+
+// Policy returns the retention policy of the address book set by the days
+func (d RetentionDays) Policy(addressBook string) RetentionPolicy {
+	const day = 24 * time.Hour
+	return RetentionPolicy{
+		AddressBook: addressBook,
+		StaleUsers:  time.Duration(d.StaleUsersDays * float64(day)),
+		Trash:       time.Duration(d.TrashDays * float64(day)),
+		Revisions:   time.Duration(d.RevisionsDays * float64(day)),
+	}
+}
+
+// RetentionScope selects the users a retention rule applies to: those of
+// AddressBook, or without one those of every address book but the Excluded
+// ones, which have policies of their own
+type RetentionScope struct {
+	AddressBook string
+	Excluded    []string
+}
+
+// RetentionResult is the outcome of one retention rule in an address book.
+// Results of the default policy have no address book.
+type RetentionResult struct {
+	AddressBook string    `json:"addressBook,omitempty"`
+	Rule        string    `json:"rule"`
+	Cutoff      time.Time `json:"cutoff"`
+	Affected    int64     `json:"affected"`
+}
+
+// RetentionReport is the outcome of applying retention policies. In a dry
+// run nothing is removed and the results count what would have been.
+type RetentionReport struct {
+	DryRun     bool               `json:"dryRun"`
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt time.Time          `json:"finishedAt"`
+	Results    []*RetentionResult `json:"results"`
+}
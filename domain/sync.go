@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tombstone marks a user that was deleted since the previous sync
+type Tombstone struct {
+	ID        primitive.ObjectID `json:"id"`
+	DeletedAt time.Time          `json:"deletedAt"`
+}
+
+// SyncResult is a page of user changes. A full result replaces everything the
+// client holds; otherwise Users are upserted and Deleted are removed.
+type SyncResult struct {
+	Full      bool         `json:"full"`
+	Users     []*User      `json:"users"`
+	Deleted   []*Tombstone `json:"deleted"`
+	NextToken string       `json:"nextToken"`
+	HasMore   bool         `json:"hasMore"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+// TagsReq changes the tags and the favorite flag of several users at once
+type TagsReq struct {
+	UserIDs  []string `json:"userIds"`
+	Add      []string `json:"add"`
+	Remove   []string `json:"remove"`
+	Favorite *bool    `json:"favorite"`
+}
+
+// UserFacets counts the users of a listing per tag and how many of them are favorites
+type UserFacets struct {
+	Tags      map[string]int64 `json:"tags"`
+	Favorites int64            `json:"favorites"`
+}
+
+// UserList is a listing of users together with its facets
+type UserList struct {
+	Users  []*User     `json:"users"`
+	Facets *UserFacets `json:"facets"`
+}
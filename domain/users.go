@@ -1,13 +1,93 @@
 package domain
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 
 
 type User struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	AddressBook string `json:"addressBook,omitempty" bson:"addressBook,omitempty"`
 	Username  string `json:"username" bson:"username,omitempty"`
 	Phone     string `json:"phone" bson:"phone,omitempty"`
+	Revision  int `json:"revision" bson:"revision,omitempty"`
+	ChangeSeq int64 `json:"changeSeq" bson:"changeSeq,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	MergedIDs  []primitive.ObjectID `json:"mergedIds,omitempty" bson:"mergedIds,omitempty"`
+	MergedInto *primitive.ObjectID `json:"mergedInto,omitempty" bson:"mergedInto,omitempty"`
+	GroupIDs   []primitive.ObjectID `json:"groupIds,omitempty" bson:"groupIds,omitempty"`
+	Tags       []string `json:"tags,omitempty" bson:"tags,omitempty"`
+	Favorite   bool `json:"favorite" bson:"favorite,omitempty"`
+	Photo      *Photo `json:"photo,omitempty" bson:"photo,omitempty"`
+	Birthday    *Date `json:"birthday,omitempty" bson:"birthday,omitempty"`
+	Anniversary *Date `json:"anniversary,omitempty" bson:"anniversary,omitempty"`
+	LastContactedAt *time.Time `json:"lastContactedAt,omitempty" bson:"lastContactedAt,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	CreatedBy string `json:"createdBy,omitempty" bson:"createdBy,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	UpdatedBy string `json:"updatedBy,omitempty" bson:"updatedBy,omitempty"`
+}
+
+// UserFilter narrows down the users returned by a listing
+type UserFilter struct {
+	GroupID  *primitive.ObjectID
+	Tags     []string // users carrying all of these tags
+	Favorite *bool
+	WithDates bool // only users with a birthday or anniversary
+	Sort string // one of the UserSorts, unordered when empty
+}
+
+// Orders of a user listing. Users never contacted come first in ascending
+// order and last in descending order.
+const (
+	SortLastContacted     = "lastContacted"
+	SortLastContactedDesc = "-lastContacted"
+	SortCreated           = "created"
+	SortCreatedDesc       = "-created"
+	SortUpdated           = "updated"
+	SortUpdatedDesc       = "-updated"
+)
+
+// UserSorts lists every order of a user listing
+var UserSorts = []string{SortLastContacted, SortLastContactedDesc, SortCreated, SortCreatedDesc, SortUpdated, SortUpdatedDesc}
+
+// Actors recorded as createdBy and updatedBy for changes without a caller
+const (
+	ActorAnonymous = "anonymous"
+	ActorRetention = "system:retention"
+)
+
+// Revision is a snapshot of a user as it was before an update
+type Revision struct {
+	ID          primitive.ObjectID `json:"-" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"userId" bson:"userId"`
+	Rev         int                `json:"rev" bson:"rev"`
+	Username    string             `json:"username" bson:"username,omitempty"`
+	Phone       string             `json:"phone" bson:"phone,omitempty"`
+	Birthday    *Date              `json:"birthday,omitempty" bson:"-"`
+	Anniversary *Date              `json:"anniversary,omitempty" bson:"-"`
+	// Dates holds the birthday and anniversary sealed together. It is empty
+	// on revisions saved before dates were kept.
+	Dates   string    `json:"-" bson:"dates,omitempty"`
+	SavedAt time.Time `json:"savedAt" bson:"savedAt"`
+}
+
+// CreateReq holds the fields a client sets when creating a user. Everything
+// else on a user is managed by the server.
+type CreateReq struct {
+	Username    string   `json:"username"`
+	Phone       string   `json:"phone"`
+	Tags        []string `json:"tags,omitempty"`
+	Birthday    *Date    `json:"birthday,omitempty"`
+	Anniversary *Date    `json:"anniversary,omitempty"`
+}
+
+// User returns the user the request creates
+func (r CreateReq) User() *User {
+	return &User{Username: r.Username, Phone: r.Phone, Tags: r.Tags, Birthday: r.Birthday, Anniversary: r.Anniversary}
 }
 
 type  UpdateReq struct {
@@ -15,4 +95,6 @@ type  UpdateReq struct {
 	Username    string `json:"username"`
 	NewUsername string `json:"newUsername,omitempty"`
 	NewPhone    string `json:"newPhone,omitempty"`
+	Birthday    *Date `json:"birthday,omitempty"`
+	Anniversary *Date `json:"anniversary,omitempty"`
 }
\ No newline at end of file
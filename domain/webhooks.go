@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Delivery statuses
+const (
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is a subscription to user lifecycle events
+type Webhook struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	URL       string             `json:"url" bson:"url"`
+	Events    []string           `json:"events" bson:"events"`
+	Secret    string             `json:"secret,omitempty" bson:"secret"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Delivery records a single attempt to deliver an event to a webhook
+type Delivery struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID   primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	EventID     string             `json:"eventId" bson:"eventId"`
+	EventType   string             `json:"eventType" bson:"eventType"`
+	Attempt     int                `json:"attempt" bson:"attempt"`
+	Status      string             `json:"status" bson:"status"`
+	StatusCode  int                `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	AttemptedAt time.Time          `json:"attemptedAt" bson:"attemptedAt"`
+}
+
+// DeadLetter is an event that could not be delivered to a webhook after all retries
+type DeadLetter struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	Event     Event              `json:"event" bson:"event"`
+	LastError string             `json:"lastError" bson:"lastError"`
+	FailedAt  time.Time          `json:"failedAt" bson:"failedAt"`
+}
+
+// PendingDelivery is an event waiting in the delivery queue of a webhook. It
+// stays queued until it is delivered or moved to the dead-letter queue, so
+// deliveries survive restarts.
+type PendingDelivery struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID `json:"webhookId" bson:"webhookId"`
+	Event         Event              `json:"event" bson:"event"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time          `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LeasedUntil   time.Time          `json:"leasedUntil" bson:"leasedUntil"`
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"findApi/internal/metrics"
 	"io"
 	"log"
 )
@@ -110,7 +111,9 @@ func unpad(data []byte, blockSize int) []byte {
 }
 
 // EncryptECB encrypts the given plaintext using AES in ECB mode
-func EncryptECB(plaintext string, key []byte) (string, error) {
+func EncryptECB(plaintext string, key []byte) (encrypted string, err error) {
+	defer func() { metrics.ObserveCrypto("encrypt", "ecb", err) }()
+
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -132,7 +135,9 @@ func EncryptECB(plaintext string, key []byte) (string, error) {
 }
 
 // DecryptECB decrypts the given hex-encoded ciphertext using AES in ECB mode
-func DecryptECB(cipherHex string, key []byte) (string, error) {
+func DecryptECB(cipherHex string, key []byte) (decrypted string, err error) {
+	defer func() { metrics.ObserveCrypto("decrypt", "ecb", err) }()
+
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return "", err
@@ -147,6 +152,9 @@ func DecryptECB(cipherHex string, key []byte) (string, error) {
 	if len(ciphertext)%aes.BlockSize != 0 {
 		return "", errors.New("invalid ciphertext length")
 	}
+	if len(ciphertext) == 0 {
+		return "", nil
+	}
 
 	plaintextBytes := make([]byte, len(ciphertext))
 	for start := 0; start < len(ciphertext); start += aes.BlockSize {
@@ -155,6 +163,9 @@ func DecryptECB(cipherHex string, key []byte) (string, error) {
 
 	// Remove padding
 	padding := int(plaintextBytes[len(plaintextBytes)-1])
+	if padding == 0 || padding > len(plaintextBytes) {
+		return "", errors.New("invalid padding")
+	}
 	plaintextBytes = plaintextBytes[:len(plaintextBytes)-padding]
 
 	return string(plaintextBytes), nil
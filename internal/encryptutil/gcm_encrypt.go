@@ -0,0 +1,71 @@
+package encryptutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"findApi/internal/metrics"
+	"io"
+)
+
+// KeySize is the size in bytes of the keys made by NewKey, selecting AES-256
+const KeySize = 32
+
+// NewKey returns a random key for EncryptGCM
+func NewKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncryptGCM encrypts and authenticates the plaintext with AES-GCM under the
+// given key. The result is the base64-encoded random nonce followed by the
+// ciphertext, so encrypting the same plaintext twice gives different results.
+func EncryptGCM(plaintext []byte, key []byte) (encrypted string, err error) {
+	defer func() { metrics.ObserveCrypto("encrypt", "gcm", err) }()
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// DecryptGCM decrypts a value made by EncryptGCM, failing if it was made
+// under another key or has been tampered with
+func DecryptGCM(encrypted string, key []byte) (decrypted []byte, err error) {
+	defer func() { metrics.ObserveCrypto("decrypt", "gcm", err) }()
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// newGCM creates an AES-GCM cipher with the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
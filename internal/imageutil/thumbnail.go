@@ -0,0 +1,53 @@
+package imageutil
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales the image down to fit in a size×size box, keeping its
+// aspect ratio, and flattens any transparency onto white. Each thumbnail
+// pixel is the average of the source pixels it covers. Images that already
+// fit keep their dimensions.
+func Thumbnail(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			dstWidth, dstHeight = size, max(1, height*size/width)
+		} else {
+			dstWidth, dstHeight = max(1, width*size/height), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := bounds.Min.Y + (y+1)*height/dstHeight
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := bounds.Min.X + (x+1)*width/dstWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			r, g, b, a = r/n, g/n, b/n, a/n
+
+			// The channels are alpha-premultiplied, so adding the missing
+			// coverage as white composites the pixel over a white background
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + 0xffff - a) >> 8),
+				G: uint8((g + 0xffff - a) >> 8),
+				B: uint8((b + 0xffff - a) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
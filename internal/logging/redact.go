@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces every masked value
+const Redacted = "[redacted]"
+
+// sensitiveKeys are attributes and key-value pairs whose value is always masked
+var sensitiveKeys = map[string]bool{
+	"phone":    true,
+	"username": true,
+	"email":    true,
+	"name":     true,
+	"notes":    true,
+}
+
+var (
+	// sensitivePair matches a sensitive key followed by its value, as in
+	// `phone: "+15551234567"` or `username=jdoe` within error messages
+	sensitivePair = regexp.MustCompile(`(?i)\b(phone|username|email|name|notes)("?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;&}]+)`)
+	// emailPattern matches email addresses
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// phonePattern matches runs of at least seven digits with the separators
+	// found in phone numbers. Digits within words, such as in hex ids and
+	// timestamps, are kept.
+	phonePattern = regexp.MustCompile(`(^|[^0-9A-Za-z])(\+?\(?\d(?:[\d ().-]*\d){6,})($|[^0-9A-Za-z:])`)
+)
+
+// Redact masks the phone numbers, email addresses and values of sensitive
+// keys in the text
+func Redact(text string) string {
+	text = sensitivePair.ReplaceAllString(text, "${1}${2}"+Redacted)
+	text = emailPattern.ReplaceAllString(text, Redacted)
+	// Keep the characters around the number
+	return phonePattern.ReplaceAllString(text, "${1}"+Redacted+"${3}")
+}
+
+// handler masks personal data in the message and attributes of every record
+// and adds the request ID and trace of the context
+type handler struct {
+	next slog.Handler
+}
+
+// NewHandler wraps the handler so personal data is masked before it is written
+func NewHandler(next slog.Handler) slog.Handler {
+	return &handler{next: next}
+}
+
+// Enabled reports whether the wrapped handler handles records at the level
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle masks the record and passes it on
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	if id := RequestID(ctx); id != "" {
+		redacted.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		redacted.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a handler masking the given attributes as well
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		redacted = append(redacted, redactAttr(attr))
+	}
+	return &handler{next: h.next.WithAttrs(redacted)}
+}
+
+// WithGroup returns a handler nesting the attributes under the group
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr masks an attribute, entirely for a sensitive key and otherwise
+// the personal data within its text
+func redactAttr(attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, 0, len(group))
+		for _, member := range group {
+			redacted = append(redacted, redactAttr(member))
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"quoted sensitive value", `user phone: "+1 555 123 4567" not found`, `user phone: [redacted] not found`},
+		{"sensitive key-value pair", "username=jdoe&limit=10", "username=[redacted]&limit=10"},
+		{"sensitive key in any case", "Phone=5551234567", "Phone=[redacted]"},
+		{"sensitive JSON field", `{"name":"Ada","id":1}`, `{"name":[redacted],"id":1}`},
+		{"email address", "sent to ada@example.com today", "sent to [redacted] today"},
+		{"phone number", "call +1 (555) 123-4567 today", "call [redacted] today"},
+		{"phone number at the end", "/users/phone/5551234567", "/users/phone/[redacted]"},
+		{"hex id", "user 64b7f3a2c9e77a0012345678 not found", "user 64b7f3a2c9e77a0012345678 not found"},
+		{"timestamp", "at 2025-01-01T10:00:00Z", "at 2025-01-01T10:00:00Z"},
+		{"short number", "page 12345 of 123456", "page 12345 of 123456"},
+		{"nothing personal", "Failed to connect", "Failed to connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("email", "ada@example.com")
+
+	logger.Error("Lookup of ada@example.com failed",
+		slog.String("phone", "+15551234567"),
+		slog.String("Username", "ada"),
+		slog.String("detail", "tried 555 123 4567"),
+		slog.Any("error", errors.New(`username="ada" taken`)),
+		slog.Group("user", slog.String("name", "Ada"), slog.Int("age", 36)),
+		slog.String("route", "/users/:id"),
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Unmarshal(%q): %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":      "Lookup of [redacted] failed",
+		"email":    Redacted,
+		"phone":    Redacted,
+		"Username": Redacted,
+		"detail":   "tried [redacted]",
+		"error":    "username=[redacted] taken",
+		"route":    "/users/:id",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+	user, _ := record["user"].(map[string]any)
+	if user["name"] != Redacted || user["age"] != float64(36) {
+		t.Errorf("user = %v, want the name masked and the age kept", record["user"])
+	}
+}
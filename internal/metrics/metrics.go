@@ -0,0 +1,133 @@
+// Package metrics holds the Prometheus metrics of the application. Every
+// label only takes values from a fixed set, such as route templates and
+// method names, so the number of series stays bounded.
+package metrics
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// HTTPRequests counts the handled requests by method, route template and status code
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration observes how long requests take by method, route template and status code
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time spent handling HTTP requests, by method, route and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// RepoDuration observes how long repository operations take
+	RepoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_operation_duration_seconds",
+		Help:    "Time spent in repository operations, by repository and operation.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"repository", "operation"})
+
+	// RepoErrors counts the repository operations that failed, not counting
+	// lookups that found nothing
+	RepoErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_operation_errors_total",
+		Help: "Failed repository operations, by repository and operation.",
+	}, []string{"repository", "operation"})
+
+	// CryptoOperations counts encryptions and decryptions by cipher and result
+	CryptoOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crypto_operations_total",
+		Help: "Encryptions and decryptions, by operation, cipher and result.",
+	}, []string{"operation", "cipher", "result"})
+
+	// RetentionRemoved counts the users and revisions removed by each retention rule
+	RetentionRemoved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retention_removed_total",
+		Help: "Records removed by the retention policy, by rule.",
+	}, []string{"rule"})
+
+	// RetentionWouldRemove counts the records each retention rule would have
+	// removed in dry runs
+	RetentionWouldRemove = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "retention_would_remove_total",
+		Help: "Records the retention policy would have removed in dry runs, by rule.",
+	}, []string{"rule"})
+
+	// RetentionDuration observes how long passes of the retention policy take
+	RetentionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "retention_run_duration_seconds",
+		Help:    "Time spent applying the retention policy, by result.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 4, 8),
+	}, []string{"result"})
+
+	// RetentionLastSuccess is when the retention policy last applied without error
+	RetentionLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "retention_last_success_timestamp_seconds",
+		Help: "Unix time of the last pass of the retention policy that succeeded.",
+	})
+)
+
+// httpMethods are the methods kept as a label, any other method is counted as OTHER
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// ObserveHTTP records a handled request. The route must be the route
+// template, not the requested path, which carries identifiers.
+func ObserveHTTP(method, route string, status int, start time.Time) {
+	if !httpMethods[method] {
+		method = "OTHER"
+	}
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(method, route, code).Inc()
+	HTTPDuration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRepo records a repository operation that started at start and ended with err
+func ObserveRepo(repository, operation string, start time.Time, err error) {
+	RepoDuration.WithLabelValues(repository, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		RepoErrors.WithLabelValues(repository, operation).Inc()
+	}
+}
+
+// ObserveCrypto records an encryption or decryption that ended with err
+func ObserveCrypto(operation, cipher string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	CryptoOperations.WithLabelValues(operation, cipher, result).Inc()
+}
+
+// ObserveRetentionRule records the records a retention rule removed, or
+// would have removed in a dry run
+func ObserveRetentionRule(rule string, affected int64, dryRun bool) {
+	if dryRun {
+		RetentionWouldRemove.WithLabelValues(rule).Add(float64(affected))
+		return
+	}
+	RetentionRemoved.WithLabelValues(rule).Add(float64(affected))
+}
+
+// ObserveRetention records a pass of the retention policy that started at start and ended with err
+func ObserveRetention(start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	RetentionDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	if err == nil {
+		RetentionLastSuccess.SetToCurrentTime()
+	}
+}
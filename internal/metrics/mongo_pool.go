@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+var (
+	// MongoConnections tracks the open connections of the Mongo pool
+	MongoConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections",
+		Help: "Open connections in the MongoDB connection pool.",
+	})
+
+	// MongoConnectionsInUse tracks the connections of the Mongo pool checked out by an operation
+	MongoConnectionsInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections_in_use",
+		Help: "Connections of the MongoDB connection pool in use by an operation.",
+	})
+
+	// MongoCheckoutFailures counts the operations that could not get a connection from the pool
+	MongoCheckoutFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_pool_checkout_failures_total",
+		Help: "Failed checkouts of a connection from the MongoDB connection pool, by reason.",
+	}, []string{"reason"})
+)
+
+// PoolMonitor returns a monitor keeping the Mongo pool metrics up to date,
+// for the client options
+func PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				MongoConnections.Inc()
+			case event.ConnectionClosed:
+				MongoConnections.Dec()
+			case event.GetSucceeded:
+				MongoConnectionsInUse.Inc()
+			case event.ConnectionReturned:
+				MongoConnectionsInUse.Dec()
+			case event.GetFailed:
+				MongoCheckoutFailures.WithLabelValues(e.Reason).Inc()
+			}
+		},
+	}
+}
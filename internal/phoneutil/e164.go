@@ -0,0 +1,64 @@
+package phoneutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrNotE164 is returned for phone numbers that have no E.164 form
+var ErrNotE164 = errors.New("phone number has no E.164 form")
+
+// E.164 numbers have up to 15 digits, country code included
+const (
+	minE164Digits = 7
+	maxE164Digits = 15
+)
+
+// E164 normalizes a phone number to E.164, "+" followed by the country code
+// and the subscriber number. Numbers starting with "+" or the international
+// prefix "00" carry their country code; other numbers are national ones,
+// which lose their trunk prefix "0" and get countryCode. Without a country
+// code national numbers have no E.164 form. Spaces, dashes, dots, slashes and
+// parentheses are ignored, any other character makes the number invalid.
+func E164(phone, countryCode string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	international := strings.HasPrefix(phone, "+")
+	phone = strings.TrimPrefix(phone, "+")
+
+	var digits strings.Builder
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -./()", r):
+		default:
+			return "", ErrNotE164
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case countryCode != "":
+		number = strings.TrimPrefix(countryCode, "+") + strings.TrimPrefix(number, "0")
+	default:
+		return "", ErrNotE164
+	}
+
+	// Country codes do not start with a zero
+	if len(number) < minE164Digits || len(number) > maxE164Digits || number[0] == '0' {
+		return "", ErrNotE164
+	}
+	return "+" + number, nil
+}
+
+// Hash returns the hex-encoded SHA-256 of a phone number in E.164, the form
+// in which clients send their contacts for discovery
+func Hash(e164 string) string {
+	sum := sha256.Sum256([]byte(e164))
+	return hex.EncodeToString(sum[:])
+}
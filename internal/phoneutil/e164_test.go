@@ -0,0 +1,50 @@
+package phoneutil
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestE164(t *testing.T) {
+	tests := []struct {
+		name        string
+		phone       string
+		countryCode string
+		want        string
+		wantErr     error
+	}{
+		{"international", "+1 (555) 123-4567", "", "+15551234567", nil},
+		{"international ignores the country code", "+44 20 7946 0000", "1", "+442079460000", nil},
+		{"international prefix", "0044 20 7946 0000", "", "+442079460000", nil},
+		{"national with trunk prefix", "020 7946 0000", "44", "+442079460000", nil},
+		{"national without trunk prefix", "555.123.4567", "1", "+15551234567", nil},
+		{"country code with plus", "555/123/4567", "+1", "+15551234567", nil},
+		{"surrounding spaces", "  +15551234567\t", "", "+15551234567", nil},
+		{"national without country code", "555 123 4567", "", "", ErrNotE164},
+		{"letters", "+1 555 CALL NOW", "", "", ErrNotE164},
+		{"extension", "+1 555 123 4567 x89", "", "", ErrNotE164},
+		{"plus inside", "1+5551234567", "1", "", ErrNotE164},
+		{"too short", "+123456", "", "", ErrNotE164},
+		{"shortest", "+1234567", "", "+1234567", nil},
+		{"longest", "+123456789012345", "", "+123456789012345", nil},
+		{"too long", "+1234567890123456", "", "", ErrNotE164},
+		{"country code starting with zero", "+0 555 123 4567", "", "", ErrNotE164},
+		{"empty", "", "1", "", ErrNotE164},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := E164(tt.phone, tt.countryCode)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("E164(%q, %q) = %q, %v, want %q, %v", tt.phone, tt.countryCode, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	// echo -n "+15551234567" | sha256sum
+	const want = "8a59780bb8cd2ba022bfa5ba2ea3b6e07af17a7d8b30c1f9b3390e36f69019e4"
+	if got := Hash("+15551234567"); got != want {
+		t.Errorf("Hash() = %q, want %q", got, want)
+	}
+}
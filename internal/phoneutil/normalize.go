@@ -0,0 +1,35 @@
+package phoneutil
+
+import "strings"
+
+// Digits strips everything but digits from a phone number, together with
+// the leading zeros of international ("00") and trunk ("0") prefixes
+func Digits(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "0")
+}
+
+// SameNumber reports whether two phone numbers are likely the same line. The
+// numbers match when their digits are equal, or when the shorter one has at
+// least minSuffixDigits digits and ends the longer one, which covers the
+// same number written with and without a country code.
+func SameNumber(a, b string) (exact bool, likely bool) {
+	const minSuffixDigits = 7
+
+	da, db := Digits(a), Digits(b)
+	if da == "" || db == "" {
+		return false, false
+	}
+	if da == db {
+		return true, true
+	}
+	if len(da) > len(db) {
+		da, db = db, da
+	}
+	return false, len(da) >= minSuffixDigits && strings.HasSuffix(db, da)
+}
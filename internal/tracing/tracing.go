@@ -0,0 +1,80 @@
+// Package tracing sets up OpenTelemetry tracing with W3C trace-context
+// propagation and holds the helpers starting spans in every layer.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"findApi/internal/logging"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName names the service in every exported span
+const ServiceName = "findApi"
+
+// tracer starts the spans of the application
+var tracer = otel.Tracer(ServiceName)
+
+// Setup installs the global tracer provider exporting spans to the named
+// exporter and the W3C trace-context propagator. The exporter is otlp,
+// configured through the standard OTEL_EXPORTER_OTLP_* variables, stdout,
+// which prints spans for testing offline, or none, which records nothing.
+// The ratio is the share of traces started here that are sampled, traces
+// continued from a caller follow the caller's decision. The returned function
+// flushes the spans not exported yet and stops the exporter.
+func Setup(ctx context.Context, exporter string, ratio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch strings.TrimSpace(exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in the context
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, opts...)
+}
+
+// End ends the span, marking it as failed when err is set. Lookups that found
+// nothing are not failures. Personal data is masked in the recorded error.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		message := logging.Redact(err.Error())
+		span.RecordError(errors.New(message))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
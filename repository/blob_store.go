@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/bootstrap"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrBlobNotFound is returned when no blob has the requested name
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects such as photos. Names are slash separated
+// paths, so the blobs of one owner can be removed together.
+type BlobStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	// Delete removes the blob with the name and every blob below it
+	Delete(ctx context.Context, name string) error
+}
+
+// NewBlobStore creates the blob store selected by PHOTO_STORE
+func NewBlobStore(db *mongo.Database, env *bootstrap.Env) (BlobStore, error) {
+	switch env.PHOTO_STORE {
+	case "local":
+		return NewLocalBlobStore(env.PHOTO_DIR)
+	case "gridfs":
+		return NewGridFSBlobStore(db, "photos")
+	default:
+		return nil, fmt.Errorf("unknown photo store %q", env.PHOTO_STORE)
+	}
+}
@@ -3,15 +3,17 @@ package db
 import (
 	"context"
 	"findApi/bootstrap"
+	"findApi/internal/metrics"
 	"log"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 func NewMongoClient(env *bootstrap.Env) *mongo.Client{
 
-	options := options.Client().ApplyURI(env.MONGO_URI)
+	options := options.Client().ApplyURI(env.MONGO_URI).SetPoolMonitor(metrics.PoolMonitor()).SetMonitor(otelmongo.NewMonitor(otelmongo.WithCommandAttributeDisabled(true)))
 	client,err := mongo.Connect(context.TODO(),options)
 
 	if err != nil{
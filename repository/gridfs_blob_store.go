@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// gridFSTimeout bounds a single blob operation, on top of the deadline of the
+// context it is called with
+const gridFSTimeout = 10 * time.Second
+
+type gridFSBlobStore struct {
+	bucket *gridfs.Bucket
+}
+
+// NewGridFSBlobStore creates a BlobStore keeping blobs in the named GridFS bucket
+func NewGridFSBlobStore(db *mongo.Database, bucketName string) (BlobStore, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(bucketName))
+	if err != nil {
+		return nil, err
+	}
+	return &gridFSBlobStore{bucket: bucket}, nil
+}
+
+// Put uploads the blob and then removes older files with the same name, so
+// the name always resolves to a complete blob
+func (s *gridFSBlobStore) Put(ctx context.Context, name string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, gridFSTimeout)
+	defer cancel()
+
+	stream, err := s.bucket.OpenUploadStream(name)
+	if err != nil {
+		return err
+	}
+	if err := stream.SetWriteDeadline(deadlineOf(ctx)); err != nil {
+		return err
+	}
+	if _, err := stream.Write(data); err != nil {
+		stream.Abort()
+		return err
+	}
+	// Leave no file behind for a caller that has gone away
+	if err := ctx.Err(); err != nil {
+		stream.Abort()
+		return err
+	}
+	if err := stream.Close(); err != nil {
+		return err
+	}
+	return s.deleteWhere(ctx, bson.M{"filename": name, "_id": bson.M{"$ne": stream.FileID}})
+}
+
+// Get downloads the newest file with the name, a chunk at a time so that it
+// stops once the caller has gone away
+func (s *gridFSBlobStore) Get(ctx context.Context, name string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, gridFSTimeout)
+	defer cancel()
+
+	stream, err := s.bucket.OpenDownloadStreamByName(name)
+	if err != nil {
+		if errors.Is(err, gridfs.ErrFileNotFound) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, err
+	}
+	defer stream.Close()
+	if err := stream.SetReadDeadline(deadlineOf(ctx)); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	chunk := make([]byte, gridfs.DefaultChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := stream.Read(chunk)
+		buf.Write(chunk[:n])
+		if errors.Is(err, io.EOF) {
+			return buf.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Delete removes the blob with the name and every blob below it
+func (s *gridFSBlobStore) Delete(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, gridFSTimeout)
+	defer cancel()
+
+	below := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name+"/")}
+	return s.deleteWhere(ctx, bson.M{"$or": bson.A{bson.M{"filename": name}, bson.M{"filename": below}}})
+}
+
+// deleteWhere removes every file matching the filter together with its chunks
+func (s *gridFSBlobStore) deleteWhere(ctx context.Context, filter bson.M) error {
+	cursor, err := s.bucket.FindContext(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var files []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &files); err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := s.bucket.DeleteContext(ctx, file.ID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deadlineOf returns the deadline of the context. The driver's upload and
+// download streams take a deadline rather than a context.
+func deadlineOf(ctx context.Context) time.Time {
+	deadline, _ := ctx.Deadline()
+	return deadline
+}
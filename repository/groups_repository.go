@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GroupsRepo interface {
+	InsertGroup(ctx context.Context, group *domain.Group) (*domain.Group, error)
+	GetGroup(ctx context.Context, id primitive.ObjectID) (*domain.Group, error)
+	FindGroups(ctx context.Context) ([]*domain.Group, error)
+	RenameGroup(ctx context.Context, id primitive.ObjectID, name string) error
+	DeleteGroup(ctx context.Context, id primitive.ObjectID) error
+}
+
+type groupRepository struct {
+	groups *mongo.Collection
+}
+
+// InsertGroup adds a new group to the collection
+func (g *groupRepository) InsertGroup(ctx context.Context, group *domain.Group) (*domain.Group, error) {
+	group.CreatedAt = time.Now().UTC()
+	res, err := g.groups.InsertOne(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	group.ID = res.InsertedID.(primitive.ObjectID)
+	return group, nil
+}
+
+// GetGroup retrieves a group by id
+func (g *groupRepository) GetGroup(ctx context.Context, id primitive.ObjectID) (*domain.Group, error) {
+	var group domain.Group
+	err := g.groups.FindOne(ctx, bson.M{"_id": id}).Decode(&group)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No group found
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// FindGroups retrieves all groups ordered by name
+func (g *groupRepository) FindGroups(ctx context.Context) ([]*domain.Group, error) {
+	var groups = make([]*domain.Group, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := g.groups.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// RenameGroup changes the name of a group
+func (g *groupRepository) RenameGroup(ctx context.Context, id primitive.ObjectID, name string) error {
+	res, err := g.groups.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"name": name}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeleteGroup removes a group from the collection
+func (g *groupRepository) DeleteGroup(ctx context.Context, id primitive.ObjectID) error {
+	delRes, err := g.groups.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if delRes.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// NewGroupRepository creates a new group repository with collection
+func NewGroupRepository(groups *mongo.Collection) GroupsRepo {
+	return &groupRepository{
+		groups: groups,
+	}
+}
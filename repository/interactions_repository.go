@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InteractionsRepo interface {
+	InsertInteraction(ctx context.Context, interaction *domain.Interaction) (*domain.Interaction, error)
+	FindInteractions(ctx context.Context, userID primitive.ObjectID, after *primitive.ObjectID, limit int64) ([]*domain.Interaction, error)
+	DeleteInteraction(ctx context.Context, id, userID primitive.ObjectID) error
+	LatestAt(ctx context.Context, userID primitive.ObjectID) (*time.Time, error)
+	DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error
+	ReassignUsers(ctx context.Context, userIDs []primitive.ObjectID, to primitive.ObjectID) error
+}
+
+type interactionRepository struct {
+	interactions *mongo.Collection
+	keys         *subjectKeys
+}
+
+// NewInteractionRepository creates a new instance of InteractionsRepo.
+// The keys collection holds the per-user keys sealing the notes.
+func NewInteractionRepository(interactions, keys *mongo.Collection, env *bootstrap.Env) InteractionsRepo {
+	return &interactionRepository{
+		interactions: interactions,
+		keys:         newSubjectKeys(keys, env.SECRET_KEY),
+	}
+}
+
+// timelineOrder sorts interactions newest first, breaking ties by id
+var timelineOrder = bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}
+
+// InsertInteraction adds an interaction with its notes sealed with the user's key
+func (i *interactionRepository) InsertInteraction(ctx context.Context, interaction *domain.Interaction) (*domain.Interaction, error) {
+	interaction.CreatedAt = time.Now().UTC()
+	stored := *interaction
+	encNotes, err := i.keys.seal(ctx, interaction.UserID, interaction.Notes)
+	if err != nil {
+		return nil, err
+	}
+	stored.Notes = encNotes
+
+	res, err := i.interactions.InsertOne(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	interaction.ID = res.InsertedID.(primitive.ObjectID)
+	return interaction, nil
+}
+
+// FindInteractions retrieves up to limit interactions of a user, newest
+// first, continuing after the interaction with the given id when there is one
+func (i *interactionRepository) FindInteractions(ctx context.Context, userID primitive.ObjectID, after *primitive.ObjectID, limit int64) ([]*domain.Interaction, error) {
+	var interactions = make([]*domain.Interaction, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"userId": userID}
+	if after != nil {
+		var last domain.Interaction
+		if err := i.interactions.FindOne(ctx, bson.M{"_id": *after, "userId": userID}).Decode(&last); err != nil {
+			return nil, err
+		}
+		// Continue with older interactions, or equally old ones with a smaller id
+		filter["$or"] = bson.A{
+			bson.M{"at": bson.M{"$lt": last.At}},
+			bson.M{"at": last.At, "_id": bson.M{"$lt": last.ID}},
+		}
+	}
+
+	opts := options.Find().SetSort(timelineOrder).SetLimit(limit)
+	cursor, err := i.interactions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &interactions); err != nil {
+		return nil, err
+	}
+
+	// Decrypt the notes before returning
+	for _, interaction := range interactions {
+		interaction.Notes, _ = i.keys.open(ctx, userID, interaction.Notes)
+	}
+	return interactions, nil
+}
+
+// DeleteInteraction removes an interaction of a user
+func (i *interactionRepository) DeleteInteraction(ctx context.Context, id, userID primitive.ObjectID) error {
+	res, err := i.interactions.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// LatestAt returns the time of the newest interaction of a user, or nil without any
+func (i *interactionRepository) LatestAt(ctx context.Context, userID primitive.ObjectID) (*time.Time, error) {
+	var latest domain.Interaction
+	opts := options.FindOne().SetSort(timelineOrder).SetProjection(bson.M{"at": 1})
+	err := i.interactions.FindOne(ctx, bson.M{"userId": userID}, opts).Decode(&latest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest.At, nil
+}
+
+// DeleteForUsers removes every interaction of the users
+func (i *interactionRepository) DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := i.interactions.DeleteMany(ctx, bson.M{"userId": bson.M{"$in": userIDs}})
+	return err
+}
+
+// ReassignUsers moves the interactions of the users over to another user,
+// resealing their notes with the key of that user
+func (i *interactionRepository) ReassignUsers(ctx context.Context, userIDs []primitive.ObjectID, to primitive.ObjectID) error {
+	filter := bson.M{"userId": bson.M{"$in": userIDs}, "notes": bson.M{"$exists": true}}
+	cursor, err := i.interactions.Find(ctx, filter, options.Find().SetProjection(bson.M{"userId": 1, "notes": 1}))
+	if err != nil {
+		return err
+	}
+	var interactions []*domain.Interaction
+	if err := cursor.All(ctx, &interactions); err != nil {
+		return err
+	}
+	for _, interaction := range interactions {
+		notes, err := i.keys.open(ctx, interaction.UserID, interaction.Notes)
+		if err != nil {
+			return err
+		}
+		if notes, err = i.keys.seal(ctx, to, notes); err != nil {
+			return err
+		}
+		update := bson.M{"$set": bson.M{"userId": to, "notes": notes}}
+		if _, err := i.interactions.UpdateOne(ctx, bson.M{"_id": interaction.ID}, update); err != nil {
+			return err
+		}
+	}
+
+	_, err = i.interactions.UpdateMany(ctx, bson.M{"userId": bson.M{"$in": userIDs}}, bson.M{"$set": bson.M{"userId": to}})
+	return err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalBlobStorePath(t *testing.T) {
+	dir := t.TempDir()
+	store := &localBlobStore{dir: dir}
+
+	tests := []struct {
+		name    string
+		blob    string
+		want    string
+		wantErr bool
+	}{
+		{"single segment", "photo", filepath.Join(dir, "photo"), false},
+		{"nested", "user/photo/original", filepath.Join(dir, "user", "photo", "original"), false},
+		{"dots within a segment", "user/photo.v2/..original", filepath.Join(dir, "user", "photo.v2", "..original"), false},
+		{"empty", "", "", true},
+		{"parent", "..", "", true},
+		{"escaping the store", "../secret", "", true},
+		{"escaping from below", "user/../../secret", "", true},
+		{"another blob's directory", "user/../other/photo", "", true},
+		{"current directory", "user/./photo", "", true},
+		{"absolute", "/etc/passwd", "", true},
+		{"trailing slash", "user/", "", true},
+		{"double slash", "user//photo", "", true},
+		{"backslash", `user\..\secret`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.path(tt.blob)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("path(%q) = %q, %v, want %q, error %v", tt.blob, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewLocalBlobStore(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore(): %v", err)
+	}
+
+	if err := store.Put(ctx, "user/photo/original", []byte("image")); err != nil {
+		t.Fatalf("Put(): %v", err)
+	}
+	if data, err := store.Get(ctx, "user/photo/original"); err != nil || string(data) != "image" {
+		t.Errorf("Get() = %q, %v, want %q", data, err, "image")
+	}
+	if _, err := store.Get(ctx, "user/photo/64"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get() of a missing blob error = %v, want %v", err, ErrBlobNotFound)
+	}
+
+	// Names outside the store are refused before touching the file system
+	if err := store.Put(ctx, "../escaped", []byte("image")); err == nil {
+		t.Error("Put() outside the store succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Put() outside the store wrote a file: %v", err)
+	}
+	if err := store.Delete(ctx, "user/.."); err == nil {
+		t.Error("Delete() of the store's parent succeeded")
+	}
+
+	// Deleting a photo removes all its sizes
+	if err := store.Delete(ctx, "user/photo"); err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	if _, err := store.Get(ctx, "user/photo/original"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrBlobNotFound)
+	}
+
+	// A caller that has gone away stores nothing
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := store.Put(cancelled, "user/photo/original", []byte("image")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Put() with a cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := store.Get(ctx, "user/photo/original"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get() after a cancelled Put() error = %v, want %v", err, ErrBlobNotFound)
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"findApi/domain"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store forgets buckets that filled up again
+const sweepInterval = time.Minute
+
+// memoryBucket is a token bucket kept in memory
+type memoryBucket struct {
+	tokens   float64
+	takenAt  time.Time
+	refilled time.Time
+}
+
+// MemoryRateLimitStore keeps the token buckets in memory, limiting every instance on its own
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	swept   time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*memoryBucket), swept: time.Now()}
+}
+
+// Take takes cost tokens from the bucket with the key
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit domain.RateLimit, cost float64) (bool, time.Duration, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: limit.Burst, takenAt: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = refill(bucket.tokens, now.Sub(bucket.takenAt), limit)
+	bucket.takenAt = now
+	// Once full again the bucket is no different from a new one
+	bucket.refilled = now.Add(limit.RefillTime())
+
+	if bucket.tokens < cost {
+		return false, waitFor(bucket.tokens, cost, limit), nil
+	}
+	bucket.tokens -= cost
+	return true, 0, nil
+}
+
+// sweep forgets the buckets that filled up again, at most once per sweepInterval
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	for key, bucket := range s.buckets {
+		if now.After(bucket.refilled) {
+			delete(s.buckets, key)
+		}
+	}
+	s.swept = now
+}
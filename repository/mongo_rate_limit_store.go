@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoRateLimitStore keeps the token buckets in a collection, so every
+// instance shares them. Buckets are updated atomically on the server, using
+// the server clock, and expire once they would have filled up again.
+type MongoRateLimitStore struct {
+	buckets  *mongo.Collection
+	timeouts timeouts
+}
+
+// NewMongoRateLimitStore creates a rate limit store keeping the buckets in the collection
+func NewMongoRateLimitStore(buckets *mongo.Collection, env *bootstrap.Env) *MongoRateLimitStore {
+	return &MongoRateLimitStore{buckets: buckets, timeouts: newTimeouts(env)}
+}
+
+// Take takes cost tokens from the bucket with the key
+func (s *MongoRateLimitStore) Take(ctx context.Context, key string, limit domain.RateLimit, cost float64) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+
+	elapsed := bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{"$$NOW", bson.M{"$ifNull": bson.A{"$takenAt", "$$NOW"}}}}, 1000}}
+	refilled := bson.M{"$min": bson.A{limit.Burst, bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", limit.Burst}},
+		bson.M{"$multiply": bson.A{elapsed, limit.Rate}},
+	}}}}
+	hasTokens := bson.M{"$gte": bson.A{"$tokens", cost}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled, "takenAt": "$$NOW"}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed":   hasTokens,
+			"tokens":    bson.M{"$cond": bson.A{hasTokens, bson.M{"$subtract": bson.A{"$tokens", cost}}, "$tokens"}},
+			"expiresAt": bson.M{"$add": bson.A{"$$NOW", limit.RefillTime().Milliseconds()}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket struct {
+		Tokens  float64 `bson:"tokens"`
+		Allowed bool    `bson:"allowed"`
+	}
+	err := s.buckets.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another instance created the bucket at the same time
+		err = s.buckets.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	if !bucket.Allowed {
+		return false, waitFor(bucket.Tokens, cost, limit), nil
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OutboxRepo interface {
+	FindPending(ctx context.Context, limit int64) ([]*domain.OutboxEntry, error)
+	FindSince(ctx context.Context, addressBook string, seq int64, limit int64) ([]*domain.Event, error)
+	ReplayHorizon(ctx context.Context) (int64, error)
+	MarkPublished(ctx context.Context, id primitive.ObjectID) error
+}
+
+type outboxRepository struct {
+	outbox     *mongo.Collection
+	counters   *mongo.Collection
+	SECRET_KEY string
+}
+
+// FindPending retrieves the oldest entries that have not been published yet
+func (o *outboxRepository) FindPending(ctx context.Context, limit int64) ([]*domain.OutboxEntry, error) {
+	var entries = make([]*domain.OutboxEntry, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "event.seq", Value: 1}, {Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := o.outbox.Find(ctx, bson.M{"publishedAt": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	// Decrypt the users carried by the events
+	for _, entry := range entries {
+		o.decryptEventUser(&entry.Event)
+	}
+	return entries, nil
+}
+
+// FindSince retrieves the events of the address book with a sequence number
+// after seq, oldest first. Events from before address books belong to the
+// default one.
+func (o *outboxRepository) FindSince(ctx context.Context, addressBook string, seq int64, limit int64) ([]*domain.Event, error) {
+	var entries = make([]*domain.OutboxEntry, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"event.seq": bson.M{"$gt": seq}, "event.addressBook": addressBook}
+	if addressBook == domain.DefaultAddressBook {
+		filter["event.addressBook"] = bson.M{"$in": bson.A{addressBook, nil}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "event.seq", Value: 1}}).SetLimit(limit)
+	cursor, err := o.outbox.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	events := make([]*domain.Event, 0, len(entries))
+	for _, entry := range entries {
+		o.decryptEventUser(&entry.Event)
+		events = append(events, &entry.Event)
+	}
+	return events, nil
+}
+
+// ReplayHorizon returns the sequence number up to which events may no longer
+// be stored: published entries expire, so the events before the oldest one
+// left are gone, and without any left every event so far is. Streams can only
+// be resumed from the horizon or later.
+func (o *outboxRepository) ReplayHorizon(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var oldest domain.OutboxEntry
+	opts := options.FindOne().SetSort(bson.D{{Key: "event.seq", Value: 1}}).SetProjection(bson.M{"event.seq": 1})
+	err := o.outbox.FindOne(ctx, bson.M{}, opts).Decode(&oldest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return readSequence(ctx, o.counters, eventsCounter)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return oldest.Event.Seq - 1, nil
+}
+
+// decryptEventUser decrypts the user carried by a stored event
+func (o *outboxRepository) decryptEventUser(event *domain.Event) {
+	if event.User == nil {
+		return
+	}
+	event.User.Username, _ = encryptutil.DecryptECB(event.User.Username, []byte(o.SECRET_KEY))
+	event.User.Phone, _ = encryptutil.DecryptECB(event.User.Phone, []byte(o.SECRET_KEY))
+}
+
+// MarkPublished records that an entry has been handed to every sink
+func (o *outboxRepository) MarkPublished(ctx context.Context, id primitive.ObjectID) error {
+	_, err := o.outbox.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"publishedAt": time.Now().UTC()}})
+	return err
+}
+
+// nextSequence increments and returns the named counter. Within a
+// transaction the counter document stays locked until commit, so sequence
+// numbers are handed out in commit order without gaps.
+func nextSequence(ctx context.Context, counters *mongo.Collection, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := counters.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// readSequence returns the current value of the named counter, or zero if it has never been used
+func readSequence(ctx context.Context, counters *mongo.Collection, name string) (int64, error) {
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := counters.FindOne(ctx, bson.M{"_id": name}).Decode(&counter)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// newOutboxEntry creates an outbox entry for the event, encrypting the user it carries
+func newOutboxEntry(event *domain.Event, key string) (*domain.OutboxEntry, error) {
+	entry := &domain.OutboxEntry{Event: *event, CreatedAt: event.OccurredAt}
+	if event.User != nil {
+		user := *event.User
+		var err error
+		if user.Username, err = encryptutil.EncryptECB(user.Username, []byte(key)); err != nil {
+			return nil, err
+		}
+		if user.Phone, err = encryptutil.EncryptECB(user.Phone, []byte(key)); err != nil {
+			return nil, err
+		}
+		entry.Event.User = &user
+	}
+	return entry, nil
+}
+
+// NewOutboxRepository creates a new outbox repository with collections and secret key.
+// The counters collection holds the sequence number of the latest event.
+func NewOutboxRepository(outbox, counters *mongo.Collection, env *bootstrap.Env) OutboxRepo {
+	return &outboxRepository{
+		outbox:     outbox,
+		counters:   counters,
+		SECRET_KEY: env.SECRET_KEY,
+	}
+}
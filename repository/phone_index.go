@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"findApi/internal/phoneutil"
+)
+
+// phoneIndex derives the fields that make a user's phone discoverable by its
+// hash, stored under "discovery" next to the encrypted phone. The blind index
+// is an HMAC of the hash, so the stored value matches a hash sent by a client
+// without being one that can be reversed by trying every number. The bucket
+// is the short hash prefix clients may ask for instead of the hash.
+type phoneIndex struct {
+	key         string
+	countryCode string
+}
+
+// newPhoneIndex creates a phone index with a key derived from the secret key.
+// National numbers are taken to be in the country with countryCode.
+func newPhoneIndex(secret, countryCode string) *phoneIndex {
+	return &phoneIndex{
+		key:         encryptutil.SignHMAC([]byte("phone discovery"), secret),
+		countryCode: countryCode,
+	}
+}
+
+// hash returns the hash of a phone in E.164, as clients compute it, and
+// false for phones without an E.164 form
+func (p *phoneIndex) hash(phone string) (string, bool) {
+	e164, err := phoneutil.E164(phone, p.countryCode)
+	if err != nil {
+		return "", false
+	}
+	return phoneutil.Hash(e164), true
+}
+
+// blind returns the blind index of a phone hash
+func (p *phoneIndex) blind(hash string) string {
+	return encryptutil.SignHMAC([]byte(hash), p.key)
+}
+
+// fields returns the discovery fields of a phone, or nil for phones that
+// cannot be discovered
+func (p *phoneIndex) fields(phone string) interface{} {
+	hash, ok := p.hash(phone)
+	if !ok {
+		return nil
+	}
+	return phoneDiscovery{Index: p.blind(hash), Bucket: hash[:domain.DiscoveryBucketLength]}
+}
+
+// phoneDiscovery is the stored shape of the discovery fields
+type phoneDiscovery struct {
+	Index  string `bson:"index"`
+	Bucket string `bson:"bucket"`
+}
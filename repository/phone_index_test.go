@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"findApi/domain"
+	"findApi/internal/phoneutil"
+	"testing"
+)
+
+func TestPhoneIndexHash(t *testing.T) {
+	index := newPhoneIndex("secret", "44")
+	want := phoneutil.Hash("+442079460000")
+
+	tests := []struct {
+		phone  string
+		want   string
+		wantOK bool
+	}{
+		{"+44 20 7946 0000", want, true},
+		{"0044 20 7946 0000", want, true},
+		{"020 7946 0000", want, true},
+		{"+1 555 123 4567", phoneutil.Hash("+15551234567"), true},
+		{"not a phone", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := index.hash(tt.phone)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("hash(%q) = %q, %v, want %q, %v", tt.phone, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPhoneIndexBlind(t *testing.T) {
+	hash := phoneutil.Hash("+442079460000")
+	index := newPhoneIndex("secret", "44")
+
+	blind := index.blind(hash)
+	if blind == hash {
+		t.Error("blind() returned the hash itself")
+	}
+	if again := newPhoneIndex("secret", "1").blind(hash); again != blind {
+		t.Errorf("blind() = %q with the same secret, want %q", again, blind)
+	}
+	if other := newPhoneIndex("other secret", "44").blind(hash); other == blind {
+		t.Error("blind() is the same under another secret")
+	}
+	if other := index.blind(phoneutil.Hash("+15551234567")); other == blind {
+		t.Error("blind() is the same for another phone")
+	}
+}
+
+func TestPhoneIndexFields(t *testing.T) {
+	index := newPhoneIndex("secret", "44")
+	hash := phoneutil.Hash("+442079460000")
+
+	fields, ok := index.fields("020 7946 0000").(phoneDiscovery)
+	if !ok {
+		t.Fatalf("fields() = %#v, want phoneDiscovery", index.fields("020 7946 0000"))
+	}
+	if fields.Index != index.blind(hash) || fields.Bucket != hash[:domain.DiscoveryBucketLength] {
+		t.Errorf("fields() = %+v, want index %q and bucket %q", fields, index.blind(hash), hash[:domain.DiscoveryBucketLength])
+	}
+	// Only the short bucket of the hash is stored as is
+	if fields.Index == hash {
+		t.Error("fields() stored the hash as the index")
+	}
+
+	if got := index.fields("not a phone"); got != nil {
+		t.Errorf("fields() = %#v for a phone without E.164 form, want nil", got)
+	}
+}
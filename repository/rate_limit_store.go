@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RateLimitStore keeps the token buckets of the rate limits
+type RateLimitStore interface {
+	// Take takes cost tokens from the bucket with the key, filling it up
+	// first for the time since the last take. Without enough tokens left it
+	// takes none and reports how long until there are.
+	Take(ctx context.Context, key string, limit domain.RateLimit, cost float64) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// NewRateLimitStore creates the rate limit store selected by RATE_LIMIT_STORE.
+// The memory store limits every instance on its own, the mongo store shares
+// the buckets between instances.
+func NewRateLimitStore(db *mongo.Database, env *bootstrap.Env) (RateLimitStore, error) {
+	switch env.RATE_LIMIT_STORE {
+	case "memory":
+		return NewMemoryRateLimitStore(), nil
+	case "mongo":
+		return NewMongoRateLimitStore(db.Collection("rate_limits"), env), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", env.RATE_LIMIT_STORE)
+	}
+}
+
+// refill returns the tokens in a bucket that held tokens at the last take,
+// elapsed ago
+func refill(tokens float64, elapsed time.Duration, limit domain.RateLimit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(limit.Burst, tokens+elapsed.Seconds()*limit.Rate)
+}
+
+// waitFor returns how long until a bucket holding tokens has cost tokens
+func waitFor(tokens, cost float64, limit domain.RateLimit) time.Duration {
+	return time.Duration((cost - tokens) / limit.Rate * float64(time.Second))
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"findApi/domain"
+	"testing"
+	"time"
+)
+
+func TestRefill(t *testing.T) {
+	limit := domain.RateLimit{Rate: 2, Burst: 10}
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"no time passed", 3, 0, 3},
+		{"partly refilled", 3, 1500 * time.Millisecond, 6},
+		{"up to the burst", 3, time.Hour, 10},
+		{"full stays full", 10, time.Second, 10},
+		{"clock went back", 3, -time.Minute, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refill(tt.tokens, tt.elapsed, limit); got != tt.want {
+				t.Errorf("refill(%v, %v) = %v, want %v", tt.tokens, tt.elapsed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens float64
+		cost   float64
+		limit  domain.RateLimit
+		want   time.Duration
+	}{
+		{"one token short", 0, 1, domain.RateLimit{Rate: 1, Burst: 5}, time.Second},
+		{"part of a token short", 0.5, 1, domain.RateLimit{Rate: 1, Burst: 5}, 500 * time.Millisecond},
+		{"slow rate", 0, 1, domain.RateLimit{Rate: 0.1, Burst: 5}, 10 * time.Second},
+		{"costly request", 1, 5, domain.RateLimit{Rate: 2, Burst: 10}, 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitFor(tt.tokens, tt.cost, tt.limit); got != tt.want {
+				t.Errorf("waitFor(%v, %v) = %v, want %v", tt.tokens, tt.cost, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	ctx := context.Background()
+	// Slow enough that no token comes back while the test runs
+	limit := domain.RateLimit{Rate: 1.0 / 3600, Burst: 3}
+	store := NewMemoryRateLimitStore()
+
+	for i := 0; i < 3; i++ {
+		if allowed, _, err := store.Take(ctx, "a", limit, 1); !allowed || err != nil {
+			t.Fatalf("Take() %d = %v, %v, want allowed within the burst", i, allowed, err)
+		}
+	}
+
+	allowed, retryAfter, err := store.Take(ctx, "a", limit, 1)
+	if allowed || err != nil {
+		t.Fatalf("Take() = %v, %v, want denied once the burst is spent", allowed, err)
+	}
+	if retryAfter <= 59*time.Minute || retryAfter > time.Hour {
+		t.Errorf("Take() retry after %v, want about an hour", retryAfter)
+	}
+
+	// Buckets are kept per key
+	if allowed, _, _ := store.Take(ctx, "b", limit, 1); !allowed {
+		t.Error("Take() denied a key with a full bucket")
+	}
+
+	// A denied take takes no tokens
+	if allowed, _, _ := store.Take(ctx, "c", limit, 4); allowed {
+		t.Error("Take() allowed a cost above the burst")
+	}
+	if allowed, _, _ := store.Take(ctx, "c", limit, 3); !allowed {
+		t.Error("Take() denied the whole burst after a denied take")
+	}
+}
+
+func TestMemoryRateLimitStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	limit := domain.RateLimit{Rate: 1, Burst: 1}
+	store := NewMemoryRateLimitStore()
+	store.Take(ctx, "idle", limit, 1)
+	store.Take(ctx, "busy", limit, 1)
+
+	now := time.Now()
+	store.buckets["idle"].refilled = now.Add(-time.Second)
+	store.buckets["busy"].refilled = now.Add(time.Hour)
+
+	// Sweeps wait for the interval
+	store.sweep(now)
+	if len(store.buckets) != 2 {
+		t.Fatalf("sweep() within the interval left %d buckets, want 2", len(store.buckets))
+	}
+
+	store.sweep(now.Add(sweepInterval))
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("sweep() kept a bucket that filled up again")
+	}
+	if _, ok := store.buckets["busy"]; !ok {
+		t.Error("sweep() forgot a bucket that is still refilling")
+	}
+}
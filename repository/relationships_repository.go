@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RelationshipsRepo interface {
+	UpsertRelationship(ctx context.Context, relationship *domain.Relationship) (*domain.Relationship, error)
+	FindRelationships(ctx context.Context, fromIDs []primitive.ObjectID, relType string) ([]*domain.Relationship, error)
+	DeleteRelationship(ctx context.Context, id primitive.ObjectID, from primitive.ObjectID) (*domain.Relationship, error)
+	DeleteEdge(ctx context.Context, from, to primitive.ObjectID, relType string) error
+	FindForUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]*domain.Relationship, error)
+	DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error
+	ReassignUsers(ctx context.Context, userIDs []primitive.ObjectID, to primitive.ObjectID) error
+}
+
+type relationshipRepository struct {
+	relationships *mongo.Collection
+	users         *mongo.Collection
+}
+
+// NewRelationshipRepository creates a new instance of RelationshipsRepo.
+// The users collection is read to hide edges to users in the trash.
+func NewRelationshipRepository(relationships, users *mongo.Collection) RelationshipsRepo {
+	return &relationshipRepository{
+		relationships: relationships,
+		users:         users,
+	}
+}
+
+// UpsertRelationship records the edge unless an identical one exists, and
+// returns the stored edge either way
+func (r *relationshipRepository) UpsertRelationship(ctx context.Context, relationship *domain.Relationship) (*domain.Relationship, error) {
+	filter := bson.M{"from": relationship.From, "to": relationship.To, "type": relationship.Type}
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": time.Now().UTC()}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored domain.Relationship
+	if err := r.relationships.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// FindRelationships retrieves the edges leaving the users, of the given type
+// or of any type when relType is empty. Edges whose target is in the trash or
+// gone are left out.
+func (r *relationshipRepository) FindRelationships(ctx context.Context, fromIDs []primitive.ObjectID, relType string) ([]*domain.Relationship, error) {
+	var relationships = make([]*domain.Relationship, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	match := bson.M{"from": bson.M{"$in": fromIDs}}
+	if relType != "" {
+		match["type"] = relType
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": r.users.Name(),
+			"let":  bson.M{"to": "$to"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$to"}}, "deletedAt": bson.M{"$exists": false}}},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "target",
+		}}},
+		{{Key: "$match", Value: bson.M{"target": bson.M{"$ne": bson.A{}}}}},
+		{{Key: "$project", Value: bson.M{"target": 0}}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: 1}}}},
+	}
+	cursor, err := r.relationships.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &relationships); err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+// DeleteRelationship removes an edge leaving the given user and returns it
+func (r *relationshipRepository) DeleteRelationship(ctx context.Context, id primitive.ObjectID, from primitive.ObjectID) (*domain.Relationship, error) {
+	var relationship domain.Relationship
+	err := r.relationships.FindOneAndDelete(ctx, bson.M{"_id": id, "from": from}).Decode(&relationship)
+	if err != nil {
+		return nil, err
+	}
+	return &relationship, nil
+}
+
+// DeleteEdge removes the edge of the type between the users, if there is one
+func (r *relationshipRepository) DeleteEdge(ctx context.Context, from, to primitive.ObjectID, relType string) error {
+	_, err := r.relationships.DeleteOne(ctx, bson.M{"from": from, "to": to, "type": relType})
+	return err
+}
+
+// FindForUsers retrieves every edge starting or ending at one of the users,
+// including edges to users in the trash
+func (r *relationshipRepository) FindForUsers(ctx context.Context, userIDs []primitive.ObjectID) ([]*domain.Relationship, error) {
+	var relationships = make([]*domain.Relationship, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := r.relationships.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"from": bson.M{"$in": userIDs}},
+		bson.M{"to": bson.M{"$in": userIDs}},
+	}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &relationships); err != nil {
+		return nil, err
+	}
+	return relationships, nil
+}
+
+// DeleteForUsers removes every edge starting or ending at one of the users
+func (r *relationshipRepository) DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.relationships.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"from": bson.M{"$in": userIDs}},
+		bson.M{"to": bson.M{"$in": userIDs}},
+	}})
+	return err
+}
+
+// ReassignUsers moves the edges of the users over to another user, as when
+// they are merged into it. Edges that would point from the user to itself,
+// or that it already has, are dropped.
+func (r *relationshipRepository) ReassignUsers(ctx context.Context, userIDs []primitive.ObjectID, to primitive.ObjectID) error {
+	cursor, err := r.relationships.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"from": bson.M{"$in": userIDs}},
+		bson.M{"to": bson.M{"$in": userIDs}},
+	}})
+	if err != nil {
+		return err
+	}
+	var edges []*domain.Relationship
+	if err := cursor.All(ctx, &edges); err != nil {
+		return err
+	}
+
+	moved := func(id primitive.ObjectID) primitive.ObjectID {
+		for _, userID := range userIDs {
+			if id == userID {
+				return to
+			}
+		}
+		return id
+	}
+	for _, edge := range edges {
+		from, target := moved(edge.From), moved(edge.To)
+		if from != target {
+			_, err := r.relationships.UpdateOne(ctx,
+				bson.M{"from": from, "to": target, "type": edge.Type},
+				bson.M{"$setOnInsert": bson.M{"createdAt": edge.CreatedAt}},
+				options.Update().SetUpsert(true))
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+		}
+		if _, err := r.relationships.DeleteOne(ctx, bson.M{"_id": edge.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RemindersRepo interface {
+	MarkNotified(ctx context.Context, reminder *domain.Reminder) (bool, error)
+	UnmarkNotified(ctx context.Context, reminder *domain.Reminder) error
+	DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error
+}
+
+type reminderRepository struct {
+	notifications *mongo.Collection
+}
+
+// NewReminderRepository creates a new instance of RemindersRepo
+func NewReminderRepository(notifications *mongo.Collection) RemindersRepo {
+	return &reminderRepository{
+		notifications: notifications,
+	}
+}
+
+// MarkNotified records that the reminder was sent. It reports false when the
+// reminder had already been recorded, so every reminder is sent once even
+// with several instances running.
+func (r *reminderRepository) MarkNotified(ctx context.Context, reminder *domain.Reminder) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := r.notifications.InsertOne(ctx, bson.M{
+		"_id":        notificationID(reminder),
+		"notifiedAt": time.Now().UTC(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnmarkNotified forgets that the reminder was sent, so it is sent again
+func (r *reminderRepository) UnmarkNotified(ctx context.Context, reminder *domain.Reminder) error {
+	_, err := r.notifications.DeleteOne(ctx, bson.M{"_id": notificationID(reminder)})
+	return err
+}
+
+// DeleteForUsers forgets every reminder sent for the users
+func (r *reminderRepository) DeleteForUsers(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	prefixes := make(bson.A, 0, len(userIDs))
+	for _, id := range userIDs {
+		prefixes = append(prefixes, bson.M{"_id": primitive.Regex{Pattern: "^" + id.Hex() + ":"}})
+	}
+	_, err := r.notifications.DeleteMany(ctx, bson.M{"$or": prefixes})
+	return err
+}
+
+// notificationID identifies a reminder for one user, kind and date
+func notificationID(reminder *domain.Reminder) string {
+	return reminder.UserID + ":" + reminder.Kind + ":" + reminder.Date
+}
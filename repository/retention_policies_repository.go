@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RetentionPoliciesRepo interface {
+	FindPolicies(ctx context.Context) ([]*domain.RetentionPolicy, error)
+	GetPolicy(ctx context.Context, addressBook string) (*domain.RetentionPolicy, error)
+	SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error
+	DeletePolicy(ctx context.Context, addressBook string) error
+}
+
+type retentionPolicyRepository struct {
+	policies *mongo.Collection
+}
+
+// FindPolicies retrieves the policies of every address book that has its own, ordered by address book
+func (r *retentionPolicyRepository) FindPolicies(ctx context.Context) ([]*domain.RetentionPolicy, error) {
+	var policies = make([]*domain.RetentionPolicy, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.policies.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &policies); err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetPolicy retrieves the policy of an address book
+func (r *retentionPolicyRepository) GetPolicy(ctx context.Context, addressBook string) (*domain.RetentionPolicy, error) {
+	var policy domain.RetentionPolicy
+	err := r.policies.FindOne(ctx, bson.M{"_id": addressBook}).Decode(&policy)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No policy of its own
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// SetPolicy adds or replaces the policy of its address book
+func (r *retentionPolicyRepository) SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error {
+	_, err := r.policies.ReplaceOne(ctx, bson.M{"_id": policy.AddressBook}, policy, options.Replace().SetUpsert(true))
+	return err
+}
+
+// DeletePolicy removes the policy of an address book, which falls back to the default one
+func (r *retentionPolicyRepository) DeletePolicy(ctx context.Context, addressBook string) error {
+	res, err := r.policies.DeleteOne(ctx, bson.M{"_id": addressBook})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// NewRetentionPolicyRepository creates a new retention policy repository with collection
+func NewRetentionPolicyRepository(policies *mongo.Collection) RetentionPoliciesRepo {
+	return &retentionPolicyRepository{
+		policies: policies,
+	}
+}
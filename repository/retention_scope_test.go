@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"findApi/domain"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestInScope(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope domain.RetentionScope
+		want  interface{}
+	}{
+		{"every address book", domain.RetentionScope{}, nil},
+		{"one address book", domain.RetentionScope{AddressBook: "work"}, "work"},
+		{"default address book", domain.RetentionScope{AddressBook: domain.DefaultAddressBook}, bson.M{"$in": bson.A{domain.DefaultAddressBook, nil}}},
+		{"all but some", domain.RetentionScope{Excluded: []string{"work"}}, bson.M{"$nin": bson.A{"work"}}},
+		{"all but the default", domain.RetentionScope{Excluded: []string{domain.DefaultAddressBook, "work"}}, bson.M{"$nin": bson.A{domain.DefaultAddressBook, nil, "work"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := inScope(bson.M{"deletedAt": bson.M{"$exists": true}}, tt.scope)
+			if _, ok := filter["deletedAt"]; !ok {
+				t.Errorf("inScope() dropped the rest of the filter: %v", filter)
+			}
+			if got := filter["addressBook"]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inScope() address book = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
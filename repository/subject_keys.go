@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/internal/encryptutil"
+	"findApi/internal/tracing"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sealedPrefix marks values sealed with a subject key. Values without it
+// were encrypted with the shared secret before subject keys existed.
+const sealedPrefix = "k1:"
+
+// subjectKeys keeps a random key per user for the personal data that is never
+// looked up, such as revisions and interaction notes. The keys are stored
+// wrapped under the secret key. Destroying a user's key makes every copy of
+// that data unreadable, including copies in backups.
+type subjectKeys struct {
+	keys   *mongo.Collection
+	secret []byte
+}
+
+// newSubjectKeys creates the subject keys kept in the given collection
+func newSubjectKeys(keys *mongo.Collection, secret string) *subjectKeys {
+	return &subjectKeys{
+		keys:   keys,
+		secret: []byte(secret),
+	}
+}
+
+// key returns the key of a user, creating it first when create is set. It
+// returns nil without a key.
+func (s *subjectKeys) key(ctx context.Context, userID primitive.ObjectID, create bool) ([]byte, error) {
+	var stored struct {
+		Key string `bson:"key"`
+	}
+	err := s.keys.FindOne(ctx, bson.M{"_id": userID}).Decode(&stored)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if !create {
+			return nil, nil
+		}
+		return s.createKey(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encryptutil.DecryptGCM(stored.Key, s.secret)
+}
+
+// createKey stores a new key for a user, or returns the one stored
+// concurrently by someone else
+func (s *subjectKeys) createKey(ctx context.Context, userID primitive.ObjectID) ([]byte, error) {
+	key, err := encryptutil.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := encryptutil.EncryptGCM(key, s.secret)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$setOnInsert": bson.M{"key": wrapped, "createdAt": time.Now().UTC()}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var stored struct {
+		Key string `bson:"key"`
+	}
+	if err := s.keys.FindOneAndUpdate(ctx, bson.M{"_id": userID}, update, opts).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return encryptutil.DecryptGCM(stored.Key, s.secret)
+}
+
+// seal encrypts a value with the key of a user, creating the key if needed
+func (s *subjectKeys) seal(ctx context.Context, userID primitive.ObjectID, plaintext string) (_ string, err error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	ctx, span := tracing.Start(ctx, "subjectKeys.seal", trace.WithAttributes(attribute.String("cipher", "gcm")))
+	defer func() { tracing.End(span, err) }()
+
+	key, err := s.key(ctx, userID, true)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := encryptutil.EncryptGCM([]byte(plaintext), key)
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + sealed, nil
+}
+
+// open decrypts a value sealed with the key of a user. Values from before
+// subject keys are decrypted with the secret key. It returns an empty string
+// once the key has been destroyed.
+func (s *subjectKeys) open(ctx context.Context, userID primitive.ObjectID, value string) (_ string, err error) {
+	if value == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return encryptutil.DecryptECB(value, s.secret)
+	}
+	ctx, span := tracing.Start(ctx, "subjectKeys.open", trace.WithAttributes(attribute.String("cipher", "gcm")))
+	defer func() { tracing.End(span, err) }()
+
+	key, err := s.key(ctx, userID, false)
+	if err != nil || key == nil {
+		return "", err
+	}
+	plaintext, err := encryptutil.DecryptGCM(strings.TrimPrefix(value, sealedPrefix), key)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// destroy deletes the keys of the users, making everything sealed with them unreadable
+func (s *subjectKeys) destroy(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := s.keys.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+	return err
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"findApi/bootstrap"
+	"time"
+)
+
+// timeouts bound the database calls of a single repository operation, on top
+// of the deadline of the context it is called with
+type timeouts struct {
+	// Read bounds looking up a single document
+	Read time.Duration
+	// Write bounds a change, including the transaction it runs in
+	Write time.Duration
+	// List bounds queries and aggregations over many documents
+	List time.Duration
+}
+
+// newTimeouts reads the timeouts from the environment
+func newTimeouts(env *bootstrap.Env) timeouts {
+	return timeouts{
+		Read:  env.DB_READ_TIMEOUT,
+		Write: env.DB_WRITE_TIMEOUT,
+		List:  env.DB_LIST_TIMEOUT,
+	}
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"findApi/domain"
+	"findApi/internal/metrics"
+	"findApi/internal/tracing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// instrumentedUsersRepo records the timing and errors of every operation of
+// the users repository in the metrics and traces each operation in a span
+type instrumentedUsersRepo struct {
+	next UsersRepo
+}
+
+// start starts tracing an operation and returns the context to run it with
+// and the function to call with its outcome
+func (r *instrumentedUsersRepo) start(ctx context.Context, operation string) (context.Context, func(err error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "UsersRepo."+operation)
+	return ctx, func(err error) {
+		metrics.ObserveRepo("users", operation, start, err)
+		tracing.End(span, err)
+	}
+}
+
+func (r *instrumentedUsersRepo) InsertUser(ctx context.Context, user *domain.User, actor string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "InsertUser")
+	defer func() { end(err) }()
+	return r.next.InsertUser(ctx, user, actor)
+}
+
+func (r *instrumentedUsersRepo) GetUser(ctx context.Context, filter bson.M) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "GetUser")
+	defer func() { end(err) }()
+	return r.next.GetUser(ctx, filter)
+}
+
+func (r *instrumentedUsersRepo) GetByPhone(ctx context.Context, phone string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "GetByPhone")
+	defer func() { end(err) }()
+	return r.next.GetByPhone(ctx, phone)
+}
+
+func (r *instrumentedUsersRepo) GetByUsername(ctx context.Context, username string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "GetByUsername")
+	defer func() { end(err) }()
+	return r.next.GetByUsername(ctx, username)
+}
+
+func (r *instrumentedUsersRepo) FindByIdentifiers(ctx context.Context, usernames, phones []string) (result []*domain.User, err error) {
+	ctx, end := r.start(ctx, "FindByIdentifiers")
+	defer func() { end(err) }()
+	return r.next.FindByIdentifiers(ctx, usernames, phones)
+}
+
+func (r *instrumentedUsersRepo) MatchPhoneHashes(ctx context.Context, hashes []string) (result []string, err error) {
+	ctx, end := r.start(ctx, "MatchPhoneHashes")
+	defer func() { end(err) }()
+	return r.next.MatchPhoneHashes(ctx, hashes)
+}
+
+func (r *instrumentedUsersRepo) FindPhoneBuckets(ctx context.Context, prefixes []string) (result map[string][]string, err error) {
+	ctx, end := r.start(ctx, "FindPhoneBuckets")
+	defer func() { end(err) }()
+	return r.next.FindPhoneBuckets(ctx, prefixes)
+}
+
+func (r *instrumentedUsersRepo) IndexPhones(ctx context.Context) (result int64, err error) {
+	ctx, end := r.start(ctx, "IndexPhones")
+	defer func() { end(err) }()
+	return r.next.IndexPhones(ctx)
+}
+
+func (r *instrumentedUsersRepo) UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "UpdateUser")
+	defer func() { end(err) }()
+	return r.next.UpdateUser(ctx, filter, user, actor)
+}
+
+func (r *instrumentedUsersRepo) DeleteUser(ctx context.Context, filter bson.M, actor string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "DeleteUser")
+	defer func() { end(err) }()
+	return r.next.DeleteUser(ctx, filter, actor)
+}
+
+func (r *instrumentedUsersRepo) FindAll(ctx context.Context, filter domain.UserFilter) (result []*domain.User, err error) {
+	ctx, end := r.start(ctx, "FindAll")
+	defer func() { end(err) }()
+	return r.next.FindAll(ctx, filter)
+}
+
+func (r *instrumentedUsersRepo) FindTrash(ctx context.Context) (result []*domain.User, err error) {
+	ctx, end := r.start(ctx, "FindTrash")
+	defer func() { end(err) }()
+	return r.next.FindTrash(ctx)
+}
+
+func (r *instrumentedUsersRepo) RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "RestoreUser")
+	defer func() { end(err) }()
+	return r.next.RestoreUser(ctx, id, actor)
+}
+
+func (r *instrumentedUsersRepo) PurgeTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) (result []primitive.ObjectID, err error) {
+	ctx, end := r.start(ctx, "PurgeTrash")
+	defer func() { end(err) }()
+	return r.next.PurgeTrash(ctx, scope, before)
+}
+
+func (r *instrumentedUsersRepo) CountTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) (result int64, err error) {
+	ctx, end := r.start(ctx, "CountTrash")
+	defer func() { end(err) }()
+	return r.next.CountTrash(ctx, scope, before)
+}
+
+func (r *instrumentedUsersRepo) FindStale(ctx context.Context, scope domain.RetentionScope, before time.Time) (result []primitive.ObjectID, err error) {
+	ctx, end := r.start(ctx, "FindStale")
+	defer func() { end(err) }()
+	return r.next.FindStale(ctx, scope, before)
+}
+
+func (r *instrumentedUsersRepo) CountRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (result int64, err error) {
+	ctx, end := r.start(ctx, "CountRevisions")
+	defer func() { end(err) }()
+	return r.next.CountRevisions(ctx, scope, before)
+}
+
+func (r *instrumentedUsersRepo) PurgeRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (result int64, err error) {
+	ctx, end := r.start(ctx, "PurgeRevisions")
+	defer func() { end(err) }()
+	return r.next.PurgeRevisions(ctx, scope, before)
+}
+
+func (r *instrumentedUsersRepo) FindRevisions(ctx context.Context, id primitive.ObjectID) (result []*domain.Revision, err error) {
+	ctx, end := r.start(ctx, "FindRevisions")
+	defer func() { end(err) }()
+	return r.next.FindRevisions(ctx, id)
+}
+
+func (r *instrumentedUsersRepo) RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "RevertUser")
+	defer func() { end(err) }()
+	return r.next.RevertUser(ctx, id, rev, actor)
+}
+
+func (r *instrumentedUsersRepo) FindChanges(ctx context.Context, since int64, limit int64) (result []*domain.User, err error) {
+	ctx, end := r.start(ctx, "FindChanges")
+	defer func() { end(err) }()
+	return r.next.FindChanges(ctx, since, limit)
+}
+
+func (r *instrumentedUsersRepo) CurrentSequence(ctx context.Context) (result int64, err error) {
+	ctx, end := r.start(ctx, "CurrentSequence")
+	defer func() { end(err) }()
+	return r.next.CurrentSequence(ctx)
+}
+
+func (r *instrumentedUsersRepo) SyncHorizon(ctx context.Context) (result int64, err error) {
+	ctx, end := r.start(ctx, "SyncHorizon")
+	defer func() { end(err) }()
+	return r.next.SyncHorizon(ctx)
+}
+
+func (r *instrumentedUsersRepo) MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, merged *domain.User, actor string, reassign func(ctx context.Context) error) (result *domain.User, err error) {
+	ctx, end := r.start(ctx, "MergeUsers")
+	defer func() { end(err) }()
+	return r.next.MergeUsers(ctx, primaryID, duplicateIDs, merged, actor, reassign)
+}
+
+func (r *instrumentedUsersRepo) AddToGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) (err error) {
+	ctx, end := r.start(ctx, "AddToGroup")
+	defer func() { end(err) }()
+	return r.next.AddToGroup(ctx, groupID, userIDs, actor)
+}
+
+func (r *instrumentedUsersRepo) RemoveFromGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) (err error) {
+	ctx, end := r.start(ctx, "RemoveFromGroup")
+	defer func() { end(err) }()
+	return r.next.RemoveFromGroup(ctx, groupID, userIDs, actor)
+}
+
+func (r *instrumentedUsersRepo) RemoveGroup(ctx context.Context, groupID primitive.ObjectID, actor string, deleteGroup func(ctx context.Context) error) (err error) {
+	ctx, end := r.start(ctx, "RemoveGroup")
+	defer func() { end(err) }()
+	return r.next.RemoveGroup(ctx, groupID, actor, deleteGroup)
+}
+
+func (r *instrumentedUsersRepo) CountGroupMembers(ctx context.Context) (result map[primitive.ObjectID]int64, err error) {
+	ctx, end := r.start(ctx, "CountGroupMembers")
+	defer func() { end(err) }()
+	return r.next.CountGroupMembers(ctx)
+}
+
+func (r *instrumentedUsersRepo) CountMembers(ctx context.Context, groupID primitive.ObjectID) (result int64, err error) {
+	ctx, end := r.start(ctx, "CountMembers")
+	defer func() { end(err) }()
+	return r.next.CountMembers(ctx, groupID)
+}
+
+func (r *instrumentedUsersRepo) TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) (err error) {
+	ctx, end := r.start(ctx, "TagUsers")
+	defer func() { end(err) }()
+	return r.next.TagUsers(ctx, userIDs, add, remove, favorite, actor)
+}
+
+func (r *instrumentedUsersRepo) FacetUsers(ctx context.Context, filter domain.UserFilter) (result *domain.UserFacets, err error) {
+	ctx, end := r.start(ctx, "FacetUsers")
+	defer func() { end(err) }()
+	return r.next.FacetUsers(ctx, filter)
+}
+
+func (r *instrumentedUsersRepo) SetPhoto(ctx context.Context, id primitive.ObjectID, photo *domain.Photo, actor string) (err error) {
+	ctx, end := r.start(ctx, "SetPhoto")
+	defer func() { end(err) }()
+	return r.next.SetPhoto(ctx, id, photo, actor)
+}
+
+func (r *instrumentedUsersRepo) SetLastContacted(ctx context.Context, id primitive.ObjectID, at *time.Time, actor string) (err error) {
+	ctx, end := r.start(ctx, "SetLastContacted")
+	defer func() { end(err) }()
+	return r.next.SetLastContacted(ctx, id, at, actor)
+}
+
+func (r *instrumentedUsersRepo) FindSubject(ctx context.Context, username, phone string) (result []*domain.User, err error) {
+	ctx, end := r.start(ctx, "FindSubject")
+	defer func() { end(err) }()
+	return r.next.FindSubject(ctx, username, phone)
+}
+
+func (r *instrumentedUsersRepo) EraseUsers(ctx context.Context, ids []primitive.ObjectID) (err error) {
+	ctx, end := r.start(ctx, "EraseUsers")
+	defer func() { end(err) }()
+	return r.next.EraseUsers(ctx, ids)
+}
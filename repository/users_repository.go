@@ -2,40 +2,126 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"findApi/bootstrap"
 	"findApi/domain"
 	"findApi/internal/encryptutil"
-	"log"
+	"findApi/internal/tracing"
+	"log/slog"
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type UsersRepo interface {
-	InsertUser(user *domain.User) (*domain.User, error)
-	GetUser(filter bson.M) (*domain.User, error)
-	GetByPhone(phone string) (*domain.User, error)
-	GetByUsername(username string) (*domain.User, error)
-	UpdateUser(filter bson.M, user *domain.User) error
-	DeleteUser(filter bson.M) error
-	FindAll() ([]*domain.User, error)
+	InsertUser(ctx context.Context, user *domain.User, actor string) (*domain.User, error)
+	GetUser(ctx context.Context, filter bson.M) (*domain.User, error)
+	GetByPhone(ctx context.Context, phone string) (*domain.User, error)
+	GetByUsername(ctx context.Context, username string) (*domain.User, error)
+	FindByIdentifiers(ctx context.Context, usernames, phones []string) ([]*domain.User, error)
+	MatchPhoneHashes(ctx context.Context, hashes []string) ([]string, error)
+	FindPhoneBuckets(ctx context.Context, prefixes []string) (map[string][]string, error)
+	IndexPhones(ctx context.Context) (int64, error)
+	UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) (*domain.User, error)
+	DeleteUser(ctx context.Context, filter bson.M, actor string) (*domain.User, error)
+	FindAll(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error)
+	FindTrash(ctx context.Context) ([]*domain.User, error)
+	RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) (*domain.User, error)
+	PurgeTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) ([]primitive.ObjectID, error)
+	CountTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error)
+	FindStale(ctx context.Context, scope domain.RetentionScope, before time.Time) ([]primitive.ObjectID, error)
+	CountRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error)
+	PurgeRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error)
+	FindRevisions(ctx context.Context, id primitive.ObjectID) ([]*domain.Revision, error)
+	RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) (*domain.User, error)
+	FindChanges(ctx context.Context, since int64, limit int64) ([]*domain.User, error)
+	CurrentSequence(ctx context.Context) (int64, error)
+	SyncHorizon(ctx context.Context) (int64, error)
+	MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, merged *domain.User, actor string, reassign func(ctx context.Context) error) (*domain.User, error)
+	AddToGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error
+	RemoveFromGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error
+	RemoveGroup(ctx context.Context, groupID primitive.ObjectID, actor string, deleteGroup func(ctx context.Context) error) error
+	CountGroupMembers(ctx context.Context) (map[primitive.ObjectID]int64, error)
+	CountMembers(ctx context.Context, groupID primitive.ObjectID) (int64, error)
+	TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) error
+	FacetUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserFacets, error)
+	SetPhoto(ctx context.Context, id primitive.ObjectID, photo *domain.Photo, actor string) error
+	SetLastContacted(ctx context.Context, id primitive.ObjectID, at *time.Time, actor string) error
+	FindSubject(ctx context.Context, username, phone string) ([]*domain.User, error)
+	EraseUsers(ctx context.Context, ids []primitive.ObjectID) error
 }
 
+// trashedUser is the stored shape of a soft-deleted user. The identifiers are
+// moved under "trash" so they drop out of the sparse unique indexes and of
+// every lookup by username or phone.
+type trashedUser struct {
+	ID          primitive.ObjectID  `bson:"_id"`
+	AddressBook string              `bson:"addressBook"`
+	ChangeSeq   int64               `bson:"changeSeq"`
+	DeletedAt   time.Time           `bson:"deletedAt"`
+	MergedInto  *primitive.ObjectID `bson:"mergedInto"`
+	Trash       struct {
+		Username string `bson:"username"`
+		Phone    string `bson:"phone"`
+	} `bson:"trash"`
+}
+
+// notDeleted matches users that are not in the trash
+var notDeleted = bson.M{"deletedAt": bson.M{"$exists": false}}
+
+// changeStamp returns the fields recorded on a user with every change: the
+// sequence number of the change, when it happened and who made it
+func changeStamp(seq int64, actor string) bson.M {
+	return bson.M{"changeSeq": seq, "updatedAt": time.Now().UTC(), "updatedBy": actor}
+}
+
+// inPipeline adapts a change stamp to the $set stage of an update pipeline.
+// The actor comes from the caller, so it is wrapped in $literal to keep one
+// starting with "$" from being read as a field path or operator.
+func inPipeline(stamp bson.M) bson.M {
+	stamp["updatedBy"] = bson.M{"$literal": stamp["updatedBy"]}
+	return stamp
+}
+
+// Counters kept in the counters collection
+const (
+	// eventsCounter hands out the sequence numbers of user changes and their events
+	eventsCounter = "events"
+	// syncHorizonCounter holds the highest sequence number of a purged user;
+	// changes up to it can no longer be reported as deletions
+	syncHorizonCounter = "sync_horizon"
+)
+
 type userRepository struct {
 	users      *mongo.Collection
+	revisions  *mongo.Collection
+	outbox     *mongo.Collection
+	counters   *mongo.Collection
+	keys       *subjectKeys
+	phones     *phoneIndex
+	timeouts   timeouts
 	SECRET_KEY string
 }
 
-// FindAll retrieves all users from the collection
-func (u *userRepository) FindAll() ([]*domain.User, error) {
+// FindAll retrieves all users from the collection matching the filter
+func (u *userRepository) FindAll(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
 	var users = make([]*domain.User, 0)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
 	defer cancel()
 
-	cursor, err := u.users.Find(ctx, bson.M{})
+	opts := options.Find()
+	if sort, ok := userSorts[filter.Sort]; ok {
+		opts.SetSort(sort)
+	}
+
+	cursor, err := u.users.Find(ctx, userQuery(filter), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -60,115 +146,1328 @@ func (u *userRepository) FindAll() ([]*domain.User, error) {
 	return users, nil
 }
 
+// userSorts maps the orders of a user listing to their sort documents. Ties
+// are broken by id, so the order is stable.
+var userSorts = map[string]bson.D{
+	domain.SortLastContacted:     {{Key: "lastContactedAt", Value: 1}, {Key: "_id", Value: 1}},
+	domain.SortLastContactedDesc: {{Key: "lastContactedAt", Value: -1}, {Key: "_id", Value: 1}},
+	domain.SortCreated:           {{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}},
+	domain.SortCreatedDesc:       {{Key: "createdAt", Value: -1}, {Key: "_id", Value: 1}},
+	domain.SortUpdated:           {{Key: "updatedAt", Value: 1}, {Key: "_id", Value: 1}},
+	domain.SortUpdatedDesc:       {{Key: "updatedAt", Value: -1}, {Key: "_id", Value: 1}},
+}
+
+// userQuery builds the query for the users outside the trash matching the filter
+func userQuery(filter domain.UserFilter) bson.M {
+	query := bson.M{"deletedAt": bson.M{"$exists": false}}
+	if filter.GroupID != nil {
+		query["groupIds"] = *filter.GroupID
+	}
+	if len(filter.Tags) > 0 {
+		query["tags"] = bson.M{"$all": filter.Tags}
+	}
+	if filter.WithDates {
+		query["$or"] = bson.A{
+			bson.M{"birthday": bson.M{"$exists": true}},
+			bson.M{"anniversary": bson.M{"$exists": true}},
+		}
+	}
+	if filter.Favorite != nil {
+		if *filter.Favorite {
+			query["favorite"] = true
+		} else {
+			query["favorite"] = bson.M{"$ne": true}
+		}
+	}
+	return query
+}
+
 // GetByUsername retrieves a user by username
-func (u *userRepository) GetByUsername(username string) (*domain.User, error) {
+func (u *userRepository) GetByUsername(ctx context.Context, username string) (*domain.User, error) {
 	// Encrypt the username for querying
-	encUsername, err := encryptutil.EncryptECB(username, []byte(u.SECRET_KEY))
+	encUsername, err := u.encrypt(ctx, username)
 	if err != nil {
 		return nil, err
 	}
 
 	filter := bson.M{"username": encUsername}
-	return u.GetUser(filter)
+	return u.GetUser(ctx, filter)
 }
 
 // GetByPhone retrieves a user by phone number
-func (u *userRepository) GetByPhone(phone string) (*domain.User, error) {
+func (u *userRepository) GetByPhone(ctx context.Context, phone string) (*domain.User, error) {
 	// Encrypt the phone for querying
-	encPhone, err := encryptutil.EncryptECB(phone, []byte(u.SECRET_KEY))
+	encPhone, err := u.encrypt(ctx, phone)
 	if err != nil {
 		return nil, err
 	}
 
 	filter := bson.M{"phone": encPhone}
-	return u.GetUser(filter)
+	return u.GetUser(ctx, filter)
+}
+
+// FindByIdentifiers retrieves the users with any of the usernames or phone numbers
+func (u *userRepository) FindByIdentifiers(ctx context.Context, usernames, phones []string) ([]*domain.User, error) {
+	var users = make([]*domain.User, 0)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	// Encrypt the identifiers for querying
+	var identifiers bson.A
+	for field, values := range map[string][]string{"username": usernames, "phone": phones} {
+		encValues := make(bson.A, 0, len(values))
+		for _, value := range values {
+			encValue, err := u.encrypt(ctx, value)
+			if err != nil {
+				return nil, err
+			}
+			encValues = append(encValues, encValue)
+		}
+		if len(encValues) > 0 {
+			identifiers = append(identifiers, bson.M{field: bson.M{"$in": encValues}})
+		}
+	}
+	if len(identifiers) == 0 {
+		return users, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := u.users.Find(ctx, bson.M{"$or": identifiers}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+
+		// Decrypt user data before returning
+		user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
+		user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+		users = append(users, &user)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// MatchPhoneHashes returns the phone hashes, out of the given ones, of users
+// in the book. Users in the trash are not matched.
+func (u *userRepository) MatchPhoneHashes(ctx context.Context, hashes []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	indexes := make(bson.A, 0, len(hashes))
+	for _, hash := range hashes {
+		indexes = append(indexes, u.phones.blind(hash))
+	}
+	values, err := u.users.Distinct(ctx, "discovery.index", bson.M{"discovery.index": bson.M{"$in": indexes}})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(values))
+	for _, value := range values {
+		if index, ok := value.(string); ok {
+			found[index] = true
+		}
+	}
+	matches := make([]string, 0, len(found))
+	for _, hash := range hashes {
+		if found[u.phones.blind(hash)] {
+			matches = append(matches, hash)
+		}
+	}
+	return matches, nil
+}
+
+// FindPhoneBuckets returns for every bucket prefix the phone hashes of the
+// users in the book within the bucket, sorted. Users in the trash are left out.
+func (u *userRepository) FindPhoneBuckets(ctx context.Context, prefixes []string) (map[string][]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	buckets := make(map[string][]string, len(prefixes))
+	in := make(bson.A, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		buckets[prefix] = []string{}
+		in = append(in, prefix)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"phone": 1})
+	cursor, err := u.users.Find(ctx, bson.M{"discovery.bucket": bson.M{"$in": in}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+
+		// Hash the decrypted phone the way clients do
+		phone, _ := encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+		hash, ok := u.phones.hash(phone)
+		if !ok {
+			continue
+		}
+		bucket, ok := buckets[hash[:domain.DiscoveryBucketLength]]
+		if ok && !slices.Contains(bucket, hash) {
+			buckets[hash[:domain.DiscoveryBucketLength]] = append(bucket, hash)
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	// Keep the order of the users out of the answer
+	for _, bucket := range buckets {
+		slices.Sort(bucket)
+	}
+	return buckets, nil
+}
+
+// IndexPhones stores the discovery fields of the users that lack them, those
+// added before phones could be discovered, including users in the trash. It
+// returns how many users it indexed.
+func (u *userRepository) IndexPhones(ctx context.Context) (int64, error) {
+	var indexed int64
+	for _, prefix := range []string{"", "trash."} {
+		filter := bson.M{prefix + "phone": bson.M{"$exists": true}, prefix + "discovery": bson.M{"$exists": false}}
+		cursor, err := u.users.Find(ctx, filter, options.Find().SetProjection(bson.M{prefix + "phone": 1}))
+		if err != nil {
+			return indexed, err
+		}
+
+		for cursor.Next(ctx) {
+			var stored struct {
+				ID    primitive.ObjectID `bson:"_id"`
+				Phone string             `bson:"phone"`
+				Trash struct {
+					Phone string `bson:"phone"`
+				} `bson:"trash"`
+			}
+			if err := cursor.Decode(&stored); err != nil {
+				cursor.Close(ctx)
+				return indexed, err
+			}
+			if prefix != "" {
+				stored.Phone = stored.Trash.Phone
+			}
+
+			// Only set the fields if the phone has not changed since it was read
+			phone, _ := encryptutil.DecryptECB(stored.Phone, []byte(u.SECRET_KEY))
+			res, err := u.users.UpdateOne(ctx,
+				bson.M{"_id": stored.ID, prefix + "phone": stored.Phone, prefix + "discovery": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{prefix + "discovery": u.phones.fields(phone)}},
+			)
+			if err != nil {
+				cursor.Close(ctx)
+				return indexed, err
+			}
+			indexed += res.ModifiedCount
+		}
+
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return indexed, err
+		}
+	}
+	return indexed, nil
 }
 
-// UpdateUser updates a user's details
-func (u *userRepository) UpdateUser(filter bson.M, user *domain.User) error {
+// UpdateUser updates a user's details and returns the updated user
+func (u *userRepository) UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) (*domain.User, error) {
 	// Prepare update data with encryption
 	updateData := bson.M{}
 	if user.Username != "" {
-		encUsername, err := encryptutil.EncryptECB(user.Username, []byte(u.SECRET_KEY))
+		encUsername, err := u.encrypt(ctx, user.Username)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		updateData["username"] = encUsername
 	}
 
 	if user.Phone != "" {
-		encPhone, err := encryptutil.EncryptECB(user.Phone, []byte(u.SECRET_KEY))
+		encPhone, err := u.encrypt(ctx, user.Phone)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		updateData["phone"] = encPhone
+		updateData["discovery"] = u.phones.fields(user.Phone)
+	}
+
+	// A date without a month clears it
+	update := bson.M{"$set": updateData}
+	unset := bson.M{}
+	for field, date := range map[string]*domain.Date{"birthday": user.Birthday, "anniversary": user.Anniversary} {
+		switch {
+		case date == nil:
+		case date.Month == 0:
+			unset[field] = ""
+		default:
+			updateData[field] = date
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
 	// Perform the update
-	updateRes, err := u.users.UpdateOne(context.TODO(), filter, bson.M{"$set": updateData})
-	if updateRes.ModifiedCount == 0 {
-		return mongo.ErrNoDocuments
+	return u.updateWithRevision(ctx, filter, update, actor)
+}
+
+// updateWithRevision applies the update to the user matching the filter,
+// stores the replaced version of the user as a revision and returns the
+// updated user
+func (u *userRepository) updateWithRevision(ctx context.Context, filter bson.M, update bson.M, actor string) (*domain.User, error) {
+	return u.transact(ctx, domain.EventUserUpdated, func(ctx mongo.SessionContext, seq int64) (*domain.User, error) {
+		return u.applyUpdate(ctx, filter, update, seq, actor)
+	})
+}
+
+// applyUpdate is updateWithRevision within a running transaction
+func (u *userRepository) applyUpdate(ctx mongo.SessionContext, filter bson.M, update bson.M, seq int64, actor string) (*domain.User, error) {
+	set, _ := update["$set"].(bson.M)
+	if set == nil {
+		set = bson.M{}
+		update["$set"] = set
 	}
-	return err
+	for field, value := range changeStamp(seq, actor) {
+		set[field] = value
+	}
+	update["$inc"] = bson.M{"revision": 1}
+
+	query := bson.M{"$and": bson.A{filter, notDeleted}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var before domain.User
+	if err := u.users.FindOneAndUpdate(ctx, query, update, opts).Decode(&before); err != nil {
+		return nil, err
+	}
+
+	// The snapshot is sealed with the user's own key, so erasing the user
+	// makes it unreadable
+	username, err := u.sealStored(ctx, before.ID, before.Username)
+	if err != nil {
+		return nil, err
+	}
+	phone, err := u.sealStored(ctx, before.ID, before.Phone)
+	if err != nil {
+		return nil, err
+	}
+	dates, err := u.sealDates(ctx, before.ID, before.Birthday, before.Anniversary)
+	if err != nil {
+		return nil, err
+	}
+	_, err = u.revisions.InsertOne(ctx, domain.Revision{
+		UserID:   before.ID,
+		Rev:      before.Revision,
+		Username: username,
+		Phone:    phone,
+		Dates:    dates,
+		SavedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.getUser(ctx, bson.M{"_id": before.ID})
+}
+
+// encrypt encrypts a username, phone or other looked up value with the
+// secret key, so equal values match
+func (u *userRepository) encrypt(ctx context.Context, value string) (_ string, err error) {
+	_, span := tracing.Start(ctx, "encrypt", trace.WithAttributes(attribute.String("cipher", "ecb")))
+	defer func() { tracing.End(span, err) }()
+	return encryptutil.EncryptECB(value, []byte(u.SECRET_KEY))
 }
 
-// DeleteUser deletes a user by filter
-func (u *userRepository) DeleteUser(filter bson.M) error {
-	delRes, err := u.users.DeleteOne(context.TODO(), filter)
-	if delRes.DeletedCount == 0 {
-		return mongo.ErrNoDocuments
+// sealStored reseals a value encrypted with the secret key under the key of the user
+func (u *userRepository) sealStored(ctx context.Context, userID primitive.ObjectID, stored string) (string, error) {
+	plaintext, err := encryptutil.DecryptECB(stored, []byte(u.SECRET_KEY))
+	if err != nil {
+		return "", err
 	}
-	return err
+	return u.keys.seal(ctx, userID, plaintext)
 }
 
-// InsertUser adds a new user to the collection
-func (u *userRepository) InsertUser(user *domain.User) (*domain.User, error) {
-	// Encrypt sensitive fields
-	encUsername, err := encryptutil.EncryptECB(user.Username, []byte(u.SECRET_KEY))
+// revisionDates are the dates of a user as sealed together on a revision
+type revisionDates struct {
+	Birthday    *domain.Date `json:"birthday,omitempty"`
+	Anniversary *domain.Date `json:"anniversary,omitempty"`
+}
+
+// sealDates seals the birthday and anniversary of a user with its own key.
+// The result is never empty, even without either date, so a revert can tell
+// a user without dates from a revision saved before dates were kept.
+func (u *userRepository) sealDates(ctx context.Context, userID primitive.ObjectID, birthday, anniversary *domain.Date) (string, error) {
+	plaintext, err := json.Marshal(revisionDates{Birthday: birthday, Anniversary: anniversary})
+	if err != nil {
+		return "", err
+	}
+	return u.keys.seal(ctx, userID, string(plaintext))
+}
+
+// openDates opens the dates sealed on a revision, returning nil for a
+// revision saved before dates were kept
+func (u *userRepository) openDates(ctx context.Context, userID primitive.ObjectID, sealed string) (*revisionDates, error) {
+	if sealed == "" {
+		return nil, nil
+	}
+	plaintext, err := u.keys.open(ctx, userID, sealed)
 	if err != nil {
 		return nil, err
 	}
+	var dates revisionDates
+	if err := json.Unmarshal([]byte(plaintext), &dates); err != nil {
+		return nil, err
+	}
+	return &dates, nil
+}
+
+// transact runs fn in a transaction together with writing an outbox entry
+// of the given event type for the user fn returns, so a change is never
+// committed without its event. fn receives the sequence number of the change,
+// which it records on the user as changeSeq.
+func (u *userRepository) transact(ctx context.Context, eventType string, fn func(ctx mongo.SessionContext, seq int64) (*domain.User, error)) (*domain.User, error) {
+	result, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		seq, err := nextSequence(sc, u.counters, eventsCounter)
+		if err != nil {
+			return nil, err
+		}
 
-	encPhone, err := encryptutil.EncryptECB(user.Phone, []byte(u.SECRET_KEY))
+		user, err := fn(sc, seq)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.recordEvent(sc, eventType, seq, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return result.(*domain.User), nil
+}
 
-	// Set encrypted values in the user struct
-	user.Username = encUsername
-	user.Phone = encPhone
+// runTransaction runs fn in a transaction, retrying it on transient errors
+func (u *userRepository) runTransaction(ctx context.Context, fn func(sc mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.Write)
+	defer cancel()
 
-	res, err := u.users.InsertOne(context.TODO(), user)
+	session, err := u.users.Database().Client().StartSession()
 	if err != nil {
 		return nil, err
 	}
-	log.Println(res.InsertedID)
-	user.ID = res.InsertedID.(primitive.ObjectID)
-	return user, nil
+	defer session.EndSession(ctx)
+
+	return session.WithTransaction(ctx, fn)
 }
 
-// GetUser retrieves a user by a generic filter and decrypts sensitive data
-func (u *userRepository) GetUser(filter bson.M) (*domain.User, error) {
-	var user domain.User
-	err := u.users.FindOne(context.TODO(), filter).Decode(&user)
+// recordEvent writes the outbox entry for a change within a running transaction
+func (u *userRepository) recordEvent(ctx mongo.SessionContext, eventType string, seq int64, user *domain.User) error {
+	event := domain.NewEvent(eventType, user)
+	event.Seq = seq
+
+	entry, err := newOutboxEntry(event, u.SECRET_KEY)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, nil // No user found
+		return err
+	}
+	_, err = u.outbox.InsertOne(ctx, entry)
+	return err
+}
+
+// FindRevisions retrieves the prior versions of a user, newest first
+func (u *userRepository) FindRevisions(ctx context.Context, id primitive.ObjectID) ([]*domain.Revision, error) {
+	var revisions = make([]*domain.Revision, 0)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "rev", Value: -1}})
+	cursor, err := u.revisions.Find(ctx, bson.M{"userId": id}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var revision domain.Revision
+		if err := cursor.Decode(&revision); err != nil {
+			return nil, err
+		}
+
+		// Decrypt revision data before returning
+		revision.Username, _ = u.keys.open(ctx, id, revision.Username)
+		revision.Phone, _ = u.keys.open(ctx, id, revision.Phone)
+		if dates, _ := u.openDates(ctx, id, revision.Dates); dates != nil {
+			revision.Birthday, revision.Anniversary = dates.Birthday, dates.Anniversary
 		}
+		revisions = append(revisions, &revision)
+	}
+
+	if err := cursor.Err(); err != nil {
 		return nil, err
 	}
 
-	// Decrypt sensitive data
-	user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
-	user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+	return revisions, nil
+}
 
-	return &user, nil
+// RevertUser restores a user to the given revision. The current version is
+// kept as a new revision, so a revert can itself be reverted.
+func (u *userRepository) RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) (*domain.User, error) {
+	var revision domain.Revision
+	readCtx, cancel := context.WithTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+	err := u.revisions.FindOne(readCtx, bson.M{"userId": id, "rev": rev}).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the values back encrypted as stored on users, clearing fields the
+	// revision did not have
+	set := bson.M{}
+	unset := bson.M{}
+	for field, sealed := range map[string]string{"username": revision.Username, "phone": revision.Phone} {
+		value, err := u.keys.open(readCtx, id, sealed)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			unset[field] = ""
+			continue
+		}
+		if set[field], err = u.encrypt(ctx, value); err != nil {
+			return nil, err
+		}
+		// The discovery fields follow the phone
+		if field == "phone" {
+			set["discovery"] = u.phones.fields(value)
+		}
+	}
+	if _, ok := set["discovery"]; !ok {
+		unset["discovery"] = ""
+	}
+
+	// Revisions saved before dates were kept leave the current dates alone
+	dates, err := u.openDates(readCtx, id, revision.Dates)
+	if err != nil {
+		return nil, err
+	}
+	if dates != nil {
+		for field, date := range map[string]*domain.Date{"birthday": dates.Birthday, "anniversary": dates.Anniversary} {
+			if date == nil {
+				unset[field] = ""
+			} else {
+				set[field] = date
+			}
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return u.updateWithRevision(ctx, bson.M{"_id": id}, update, actor)
 }
 
-// NewUserRepository creates a new user repository with collection and secret key
-func NewUserRepository(users *mongo.Collection, env *bootstrap.Env) UsersRepo {
-	return &userRepository{
-		users:      users,
-		SECRET_KEY: env.SECRET_KEY,
+// DeleteUser moves a user matching the filter to the trash and returns the deleted user
+func (u *userRepository) DeleteUser(ctx context.Context, filter bson.M, actor string) (*domain.User, error) {
+	return u.transact(ctx, domain.EventUserDeleted, func(ctx mongo.SessionContext, seq int64) (*domain.User, error) {
+		return u.trashUser(ctx, filter, seq, actor, bson.M{})
+	})
+}
+
+// trashUser moves a user matching the filter to the trash within a running
+// transaction, setting the extra fields on it
+func (u *userRepository) trashUser(ctx mongo.SessionContext, filter bson.M, seq int64, actor string, extra bson.M) (*domain.User, error) {
+	// Only users that are not already trashed can be deleted
+	query := bson.M{"$and": bson.A{filter, notDeleted}}
+	set := inPipeline(changeStamp(seq, actor))
+	set["deletedAt"] = time.Now().UTC()
+	set["trash"] = bson.M{"username": "$username", "phone": "$phone", "discovery": "$discovery"}
+	for field, value := range extra {
+		set[field] = value
+	}
+	update := bson.A{
+		bson.M{"$set": set},
+		bson.M{"$unset": bson.A{"username", "phone", "discovery"}},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var trashed trashedUser
+	if err := u.users.FindOneAndUpdate(ctx, query, update, opts).Decode(&trashed); err != nil {
+		return nil, err
+	}
+	return u.fromTrash(&trashed), nil
+}
+
+// MergeUsers moves the duplicates to the trash, marking them as merged into
+// the primary, and sets the merged username, phone, groups and tags on the primary. Both
+// happen in one transaction, so the primary can take over a duplicate's
+// username or phone without tripping the unique indexes. reassign runs within
+// the same transaction to move whatever else belongs to the duplicates, so a
+// failed merge leaves nothing half moved.
+func (u *userRepository) MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, merged *domain.User, actor string, reassign func(ctx context.Context) error) (*domain.User, error) {
+	// Prepare update data with encryption
+	updateData := bson.M{}
+	if merged.Username != "" {
+		encUsername, err := u.encrypt(ctx, merged.Username)
+		if err != nil {
+			return nil, err
+		}
+		updateData["username"] = encUsername
+	}
+	if merged.Phone != "" {
+		encPhone, err := u.encrypt(ctx, merged.Phone)
+		if err != nil {
+			return nil, err
+		}
+		updateData["phone"] = encPhone
+		updateData["discovery"] = u.phones.fields(merged.Phone)
+	}
+	addToSet := bson.M{"mergedIds": bson.M{"$each": duplicateIDs}}
+	if len(merged.GroupIDs) > 0 {
+		addToSet["groupIds"] = bson.M{"$each": merged.GroupIDs}
+	}
+	if len(merged.Tags) > 0 {
+		addToSet["tags"] = bson.M{"$each": merged.Tags}
+	}
+	if merged.Favorite {
+		updateData["favorite"] = true
+	}
+	if merged.LastContactedAt != nil {
+		updateData["lastContactedAt"] = merged.LastContactedAt
+	}
+	update := bson.M{
+		"$set":      updateData,
+		"$addToSet": addToSet,
+	}
+
+	result, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		for _, id := range duplicateIDs {
+			seq, err := nextSequence(sc, u.counters, eventsCounter)
+			if err != nil {
+				return nil, err
+			}
+			duplicate, err := u.trashUser(sc, bson.M{"_id": id}, seq, actor, bson.M{"mergedInto": primaryID})
+			if err != nil {
+				return nil, err
+			}
+			if err := u.recordEvent(sc, domain.EventUserDeleted, seq, duplicate); err != nil {
+				return nil, err
+			}
+		}
+		if err := reassign(sc); err != nil {
+			return nil, err
+		}
+
+		seq, err := nextSequence(sc, u.counters, eventsCounter)
+		if err != nil {
+			return nil, err
+		}
+		primary, err := u.applyUpdate(sc, bson.M{"_id": primaryID}, update, seq, actor)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.recordEvent(sc, domain.EventUserUpdated, seq, primary); err != nil {
+			return nil, err
+		}
+		return primary, nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result.(*domain.User), nil
+}
+
+// AddToGroup adds the users to a group. It fails without changing anything
+// if one of the users does not exist or is in the trash.
+func (u *userRepository) AddToGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error {
+	return u.updateMembers(ctx, userIDs, bson.M{"$addToSet": bson.M{"groupIds": groupID}}, actor)
+}
+
+// RemoveFromGroup removes the users from a group
+func (u *userRepository) RemoveFromGroup(ctx context.Context, groupID primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error {
+	return u.updateMembers(ctx, userIDs, bson.M{"$pull": bson.M{"groupIds": groupID}}, actor)
+}
+
+// RemoveGroup removes a group from every user, including users in the trash.
+// deleteGroup runs within the same transaction, so the group and its
+// memberships go together.
+func (u *userRepository) RemoveGroup(ctx context.Context, groupID primitive.ObjectID, actor string, deleteGroup func(ctx context.Context) error) error {
+	_, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if err := deleteGroup(sc); err != nil {
+			return nil, err
+		}
+
+		// Users in the trash are no longer synced, so they are updated without an event
+		trashed := bson.M{"groupIds": groupID, "deletedAt": bson.M{"$exists": true}}
+		if _, err := u.users.UpdateMany(sc, trashed, bson.M{"$pull": bson.M{"groupIds": groupID}}); err != nil {
+			return nil, err
+		}
+
+		ids, err := u.users.Distinct(sc, "_id", bson.M{"groupIds": groupID})
+		if err != nil {
+			return nil, err
+		}
+		userIDs := make([]primitive.ObjectID, 0, len(ids))
+		for _, id := range ids {
+			userIDs = append(userIDs, id.(primitive.ObjectID))
+		}
+		return nil, u.stampEach(sc, userIDs, actor, func(stamp bson.M) interface{} {
+			return bson.M{"$pull": bson.M{"groupIds": groupID}, "$set": stamp}
+		})
+	})
+	return err
+}
+
+// updateMembers applies a membership update to each user in one transaction,
+// recording a change and an event per user
+func (u *userRepository) updateMembers(ctx context.Context, userIDs []primitive.ObjectID, update bson.M, actor string) error {
+	return u.updateEach(ctx, userIDs, actor, func(stamp bson.M) interface{} {
+		update["$set"] = stamp
+		return update
+	})
+}
+
+// TagUsers adds and removes tags and sets the favorite flag on each user in
+// one transaction. It fails without changing anything if one of the users
+// does not exist or is in the trash.
+func (u *userRepository) TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) error {
+	return u.updateEach(ctx, userIDs, actor, func(set bson.M) interface{} {
+		set = inPipeline(set)
+		if len(add) > 0 || len(remove) > 0 {
+			// Tags are wrapped in $literal so a tag starting with "$" is not read as a field path
+			tags := bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, bson.M{"$literal": append([]string{}, add...)}}}
+			set["tags"] = bson.M{"$setDifference": bson.A{tags, bson.M{"$literal": append([]string{}, remove...)}}}
+		}
+		if favorite != nil {
+			set["favorite"] = *favorite
+		}
+		return mongo.Pipeline{{{Key: "$set", Value: set}}}
+	})
+}
+
+// SetPhoto sets the photo of a user outside the trash, or removes it when photo is nil
+func (u *userRepository) SetPhoto(ctx context.Context, id primitive.ObjectID, photo *domain.Photo, actor string) error {
+	return u.updateEach(ctx, []primitive.ObjectID{id}, actor, func(set bson.M) interface{} {
+		if photo == nil {
+			return bson.M{"$set": set, "$unset": bson.M{"photo": ""}}
+		}
+		set["photo"] = photo
+		return bson.M{"$set": set}
+	})
+}
+
+// SetLastContacted sets when a user outside the trash was last contacted, or removes it when at is nil
+func (u *userRepository) SetLastContacted(ctx context.Context, id primitive.ObjectID, at *time.Time, actor string) error {
+	return u.updateEach(ctx, []primitive.ObjectID{id}, actor, func(set bson.M) interface{} {
+		if at == nil {
+			return bson.M{"$set": set, "$unset": bson.M{"lastContactedAt": ""}}
+		}
+		set["lastContactedAt"] = *at
+		return bson.M{"$set": set}
+	})
+}
+
+// updateEach applies the update built from each user's change stamp in one
+// transaction, recording a change and an event per user
+func (u *userRepository) updateEach(ctx context.Context, userIDs []primitive.ObjectID, actor string, update func(stamp bson.M) interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	_, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, u.stampEach(sc, userIDs, actor, update)
+	})
+	return err
+}
+
+// stampEach updates each user within a running transaction, recording a change and an event per user
+func (u *userRepository) stampEach(sc mongo.SessionContext, userIDs []primitive.ObjectID, actor string, update func(stamp bson.M) interface{}) error {
+	for _, id := range userIDs {
+		seq, err := nextSequence(sc, u.counters, eventsCounter)
+		if err != nil {
+			return err
+		}
+
+		res, err := u.users.UpdateOne(sc, bson.M{"_id": id, "deletedAt": bson.M{"$exists": false}}, update(changeStamp(seq, actor)))
+		if err != nil {
+			return err
+		}
+		if res.MatchedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+
+		user, err := u.getUser(sc, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if err := u.recordEvent(sc, domain.EventUserUpdated, seq, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountGroupMembers counts the users outside the trash in every group
+func (u *userRepository) CountGroupMembers(ctx context.Context) (map[primitive.ObjectID]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted}},
+		{{Key: "$unwind", Value: "$groupIds"}},
+		{{Key: "$group", Value: bson.M{"_id": "$groupIds", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := u.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountMembers counts the users outside the trash in one group
+func (u *userRepository) CountMembers(ctx context.Context, groupID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	return u.users.CountDocuments(ctx, bson.M{"groupIds": groupID, "deletedAt": bson.M{"$exists": false}})
+}
+
+// FacetUsers counts the users matching the filter per tag and how many of them are favorites
+func (u *userRepository) FacetUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserFacets, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: userQuery(filter)}},
+		{{Key: "$facet", Value: bson.M{
+			"tags": bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+			},
+			"favorites": bson.A{
+				bson.M{"$match": bson.M{"favorite": true}},
+				bson.M{"$count": "count"},
+			},
+		}}},
+	}
+	cursor, err := u.users.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Tags []struct {
+			Tag   string `bson:"_id"`
+			Count int64  `bson:"count"`
+		} `bson:"tags"`
+		Favorites []struct {
+			Count int64 `bson:"count"`
+		} `bson:"favorites"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	facets := &domain.UserFacets{Tags: make(map[string]int64)}
+	if len(rows) == 0 {
+		return facets, nil
+	}
+	for _, row := range rows[0].Tags {
+		facets.Tags[row.Tag] = row.Count
+	}
+	if len(rows[0].Favorites) > 0 {
+		facets.Favorites = rows[0].Favorites[0].Count
+	}
+	return facets, nil
+}
+
+// FindTrash retrieves all soft-deleted users, most recently deleted first
+func (u *userRepository) FindTrash(ctx context.Context) ([]*domain.User, error) {
+	var users = make([]*domain.User, 0)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := u.users.Find(ctx, bson.M{"deletedAt": bson.M{"$exists": true}, "erased": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var trashed trashedUser
+		if err := cursor.Decode(&trashed); err != nil {
+			return nil, err
+		}
+
+		users = append(users, u.fromTrash(&trashed))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// RestoreUser moves a user out of the trash. It fails with a duplicate key
+// error if the username or phone has been taken in the meantime.
+func (u *userRepository) RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) (*domain.User, error) {
+	filter := bson.M{"_id": id, "deletedAt": bson.M{"$exists": true}, "erased": bson.M{"$ne": true}}
+	set := bson.M{"username": "$trash.username", "phone": "$trash.phone", "discovery": "$trash.discovery"}
+	update := bson.A{
+		bson.M{"$set": set},
+		bson.M{"$unset": bson.A{"deletedAt", "trash"}},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	return u.transact(ctx, domain.EventUserRestored, func(ctx mongo.SessionContext, seq int64) (*domain.User, error) {
+		for field, value := range inPipeline(changeStamp(seq, actor)) {
+			set[field] = value
+		}
+		var user domain.User
+		if err := u.users.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user); err != nil {
+			return nil, err
+		}
+
+		// Decrypt sensitive data
+		user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
+		user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+		return &user, nil
+	})
+}
+
+// fromTrash converts a soft-deleted user to a user with decrypted data
+func (u *userRepository) fromTrash(trashed *trashedUser) *domain.User {
+	user := domain.User{ID: trashed.ID, AddressBook: trashed.AddressBook, ChangeSeq: trashed.ChangeSeq, DeletedAt: &trashed.DeletedAt, MergedInto: trashed.MergedInto}
+	user.Username, _ = encryptutil.DecryptECB(trashed.Trash.Username, []byte(u.SECRET_KEY))
+	user.Phone, _ = encryptutil.DecryptECB(trashed.Trash.Phone, []byte(u.SECRET_KEY))
+	return &user
+}
+
+// PurgeTrash permanently removes users in the scope that were trashed before
+// the given time and returns their ids
+func (u *userRepository) PurgeTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) ([]primitive.ObjectID, error) {
+	// The users are read and removed in one transaction, so a user restored
+	// in the meantime is neither removed nor loses its revisions and key
+	result, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		filter := inScope(bson.M{"deletedAt": bson.M{"$lte": before}}, scope)
+		ids, err := u.users.Distinct(sc, "_id", filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []primitive.ObjectID(nil), nil
+		}
+
+		// Purged users vanish without a tombstone, so sync tokens from before the
+		// newest of them can no longer be served
+		var newest trashedUser
+		opts := options.FindOne().SetSort(bson.D{{Key: "changeSeq", Value: -1}})
+		if err := u.users.FindOne(sc, filter, opts).Decode(&newest); err != nil {
+			return nil, err
+		}
+		_, err = u.counters.UpdateOne(sc, bson.M{"_id": syncHorizonCounter}, bson.M{"$max": bson.M{"seq": newest.ChangeSeq}}, options.Update().SetUpsert(true))
+		if err != nil {
+			return nil, err
+		}
+
+		// Remove the users together with their revision history and keys
+		purged := make([]primitive.ObjectID, 0, len(ids))
+		for _, id := range ids {
+			purged = append(purged, id.(primitive.ObjectID))
+		}
+		if _, err := u.users.DeleteMany(sc, bson.M{"_id": bson.M{"$in": purged}, "deletedAt": bson.M{"$lte": before}}); err != nil {
+			return nil, err
+		}
+		if _, err := u.revisions.DeleteMany(sc, bson.M{"userId": bson.M{"$in": purged}}); err != nil {
+			return nil, err
+		}
+		if err := u.keys.destroy(sc, purged); err != nil {
+			return nil, err
+		}
+		return purged, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]primitive.ObjectID), nil
+}
+
+// CountTrash counts the users in the scope that were trashed before the given time
+func (u *userRepository) CountTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	return u.users.CountDocuments(ctx, inScope(bson.M{"deletedAt": bson.M{"$lte": before}}, scope))
+}
+
+// FindStale retrieves the ids of the users in the scope outside the trash
+// that have not changed since the given time. Users from before changes were
+// timestamped count from their creation, which their id records.
+func (u *userRepository) FindStale(ctx context.Context, scope domain.RetentionScope, before time.Time) ([]primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	filter := bson.M{
+		"deletedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"updatedAt": bson.M{"$lt": before}},
+			bson.M{"updatedAt": bson.M{"$exists": false}, "_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(before)}},
+		},
+	}
+	ids, err := u.users.Distinct(ctx, "_id", inScope(filter, scope))
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		stale = append(stale, id.(primitive.ObjectID))
+	}
+	return stale, nil
+}
+
+// CountRevisions counts the revisions of users in the scope saved before the given time
+func (u *userRepository) CountRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	filter, err := u.revisionsInScope(ctx, scope, before)
+	if err != nil {
+		return 0, err
+	}
+	return u.revisions.CountDocuments(ctx, filter)
+}
+
+// PurgeRevisions removes the revisions of users in the scope saved before
+// the given time and returns how many there were
+func (u *userRepository) PurgeRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	filter, err := u.revisionsInScope(ctx, scope, before)
+	if err != nil {
+		return 0, err
+	}
+	res, err := u.revisions.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// revisionsInScope returns the filter matching the revisions of users in the
+// scope saved before the given time. Revisions do not record the address
+// book, so unless the scope covers every user they are matched by user.
+func (u *userRepository) revisionsInScope(ctx context.Context, scope domain.RetentionScope, before time.Time) (bson.M, error) {
+	filter := bson.M{"savedAt": bson.M{"$lt": before}}
+	if scope.AddressBook == "" && len(scope.Excluded) == 0 {
+		return filter, nil
+	}
+
+	ids, err := u.users.Distinct(ctx, "_id", inScope(bson.M{}, scope))
+	if err != nil {
+		return nil, err
+	}
+	filter["userId"] = bson.M{"$in": ids}
+	return filter, nil
+}
+
+// inScope adds the address books of the scope to a filter on users. Users
+// from before address books belong to the default one.
+func inScope(filter bson.M, scope domain.RetentionScope) bson.M {
+	switch {
+	case scope.AddressBook == domain.DefaultAddressBook:
+		filter["addressBook"] = bson.M{"$in": bson.A{scope.AddressBook, nil}}
+	case scope.AddressBook != "":
+		filter["addressBook"] = scope.AddressBook
+	case len(scope.Excluded) > 0:
+		excluded := make(bson.A, 0, len(scope.Excluded)+1)
+		for _, addressBook := range scope.Excluded {
+			excluded = append(excluded, addressBook)
+			if addressBook == domain.DefaultAddressBook {
+				excluded = append(excluded, nil)
+			}
+		}
+		filter["addressBook"] = bson.M{"$nin": excluded}
+	}
+	return filter
+}
+
+// FindSubject retrieves every user, in or out of the trash, whose username
+// or phone is one of the given ones. Erased users are left out.
+func (u *userRepository) FindSubject(ctx context.Context, username, phone string) ([]*domain.User, error) {
+	var users = make([]*domain.User, 0)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	var identifiers bson.A
+	for field, value := range map[string]string{"username": username, "phone": phone} {
+		if value == "" {
+			continue
+		}
+		encValue, err := u.encrypt(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		identifiers = append(identifiers, bson.M{field: encValue}, bson.M{"trash." + field: encValue})
+	}
+	if len(identifiers) == 0 {
+		return users, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := u.users.Find(ctx, bson.M{"$or": identifiers, "erased": bson.M{"$ne": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var stored struct {
+			domain.User `bson:",inline"`
+			Trash       struct {
+				Username string `bson:"username"`
+				Phone    string `bson:"phone"`
+			} `bson:"trash"`
+		}
+		if err := cursor.Decode(&stored); err != nil {
+			return nil, err
+		}
+
+		// Decrypt user data before returning, taking it from the trash for deleted users
+		user := stored.User
+		if user.DeletedAt != nil {
+			user.Username, user.Phone = stored.Trash.Username, stored.Trash.Phone
+		}
+		user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
+		user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+		users = append(users, &user)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// EraseUsers replaces the users with tombstones in one transaction, recording
+// a deletion event carrying only the id of each. The events of the users
+// still waiting in the outbox, their revisions and their keys are removed
+// with them, so the personal data sealed with the keys becomes unreadable
+// wherever a copy of it is left.
+func (u *userRepository) EraseUsers(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := u.runTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := u.outbox.DeleteMany(sc, bson.M{"event.user._id": bson.M{"$in": ids}}); err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			seq, err := nextSequence(sc, u.counters, eventsCounter)
+			if err != nil {
+				return nil, err
+			}
+
+			// The tombstone keeps the address book, which says nothing about the subject
+			var erased struct {
+				AddressBook string `bson:"addressBook"`
+			}
+			filter := bson.M{"_id": id, "erased": bson.M{"$ne": true}}
+			opts := options.FindOne().SetProjection(bson.M{"addressBook": 1})
+			if err := u.users.FindOne(sc, filter, opts).Decode(&erased); err != nil {
+				return nil, err
+			}
+
+			deletedAt := time.Now().UTC()
+			tombstone := bson.M{"changeSeq": seq, "deletedAt": deletedAt, "erased": true}
+			if erased.AddressBook != "" {
+				tombstone["addressBook"] = erased.AddressBook
+			}
+			if _, err := u.users.ReplaceOne(sc, filter, tombstone); err != nil {
+				return nil, err
+			}
+
+			user := &domain.User{ID: id, AddressBook: erased.AddressBook, ChangeSeq: seq, DeletedAt: &deletedAt}
+			if err := u.recordEvent(sc, domain.EventUserDeleted, seq, user); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := u.revisions.DeleteMany(sc, bson.M{"userId": bson.M{"$in": ids}}); err != nil {
+			return nil, err
+		}
+		return nil, u.keys.destroy(sc, ids)
+	})
+	return err
+}
+
+// FindChanges retrieves users changed after the given sequence number in
+// the order they changed. Users in the trash are returned as tombstones
+// carrying only their id, sequence number and deletion time.
+func (u *userRepository) FindChanges(ctx context.Context, since int64, limit int64) ([]*domain.User, error) {
+	var users = make([]*domain.User, 0)
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.List)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "changeSeq", Value: 1}}).SetLimit(limit)
+	cursor, err := u.users.Find(ctx, bson.M{"changeSeq": bson.M{"$gt": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+
+		// Decrypt user data before returning
+		if user.DeletedAt == nil {
+			user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
+			user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+		}
+		users = append(users, &user)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CurrentSequence returns the sequence number of the latest change
+func (u *userRepository) CurrentSequence(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+
+	return readSequence(ctx, u.counters, eventsCounter)
+}
+
+// SyncHorizon returns the highest sequence number of a purged user
+func (u *userRepository) SyncHorizon(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+
+	return readSequence(ctx, u.counters, syncHorizonCounter)
+}
+
+// InsertUser adds a new user to the collection and returns it with its id
+func (u *userRepository) InsertUser(ctx context.Context, user *domain.User, actor string) (*domain.User, error) {
+	// Encrypt sensitive fields
+	encUsername, err := u.encrypt(ctx, user.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	encPhone, err := u.encrypt(ctx, user.Phone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set the server-managed fields, overriding whatever the caller passed, and
+	// the encrypted values in a copy of the user struct
+	now := time.Now().UTC()
+	user.Revision = 1
+	user.CreatedAt, user.UpdatedAt = &now, &now
+	user.CreatedBy, user.UpdatedBy = actor, actor
+	var stored struct {
+		domain.User `bson:",inline"`
+		Discovery   interface{} `bson:"discovery"`
+	}
+	stored.User = *user
+	stored.Username = encUsername
+	stored.Phone = encPhone
+	stored.Discovery = u.phones.fields(user.Phone)
+
+	return u.transact(ctx, domain.EventUserCreated, func(ctx mongo.SessionContext, seq int64) (*domain.User, error) {
+		stored.ChangeSeq = seq
+		res, err := u.users.InsertOne(ctx, stored)
+		if err != nil {
+			return nil, err
+		}
+		slog.DebugContext(ctx, "Inserted user", "id", res.InsertedID)
+		user.ID = res.InsertedID.(primitive.ObjectID)
+		user.ChangeSeq = seq
+		return user, nil
+	})
+}
+
+// GetUser retrieves a user by a generic filter and decrypts sensitive data
+func (u *userRepository) GetUser(ctx context.Context, filter bson.M) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.timeouts.Read)
+	defer cancel()
+
+	return u.getUser(ctx, filter)
+}
+
+// getUser retrieves a user by a generic filter within the given context
+func (u *userRepository) getUser(ctx context.Context, filter bson.M) (*domain.User, error) {
+	var user domain.User
+	err := u.users.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No user found
+		}
+		return nil, err
+	}
+
+	// Decrypt sensitive data
+	user.Username, _ = encryptutil.DecryptECB(user.Username, []byte(u.SECRET_KEY))
+	user.Phone, _ = encryptutil.DecryptECB(user.Phone, []byte(u.SECRET_KEY))
+
+	return &user, nil
+}
+
+// NewUserRepository creates a new user repository with collections and secret key.
+// The keys collection holds the per-user keys sealing the revisions. Every
+// operation is timed for the metrics.
+func NewUserRepository(users, revisions, outbox, counters, keys *mongo.Collection, env *bootstrap.Env) UsersRepo {
+	return &instrumentedUsersRepo{next: &userRepository{
+		users:      users,
+		revisions:  revisions,
+		outbox:     outbox,
+		counters:   counters,
+		keys:       newSubjectKeys(keys, env.SECRET_KEY),
+		phones:     newPhoneIndex(env.SECRET_KEY, env.PHONE_COUNTRY_CODE),
+		timeouts:   newTimeouts(env),
+		SECRET_KEY: env.SECRET_KEY,
+	}}
 }
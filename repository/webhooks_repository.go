@@ -0,0 +1,334 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhooksRepo interface {
+	InsertWebhook(ctx context.Context, webhook *domain.Webhook) (*domain.Webhook, error)
+	GetWebhook(ctx context.Context, id primitive.ObjectID) (*domain.Webhook, error)
+	FindWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+	FindWebhooksForEvent(ctx context.Context, eventType string) ([]*domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id primitive.ObjectID) error
+	InsertDelivery(ctx context.Context, delivery *domain.Delivery) error
+	FindDeliveries(ctx context.Context, webhookID primitive.ObjectID) ([]*domain.Delivery, error)
+	InsertDeadLetter(ctx context.Context, deadLetter *domain.DeadLetter) error
+	FindDeadLetters(ctx context.Context) ([]*domain.DeadLetter, error)
+	TakeDeadLetter(ctx context.Context, id primitive.ObjectID) (*domain.DeadLetter, error)
+	DeleteDeadLettersForUsers(ctx context.Context, userIDs []primitive.ObjectID) error
+	EnqueueDeliveries(ctx context.Context, webhooks []*domain.Webhook, event *domain.Event) error
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.PendingDelivery, error)
+	RescheduleDelivery(ctx context.Context, id primitive.ObjectID, attempts int, next time.Time) error
+	CompleteDelivery(ctx context.Context, id primitive.ObjectID) error
+	DeleteDeliveriesForUsers(ctx context.Context, userIDs []primitive.ObjectID) error
+}
+
+type webhookRepository struct {
+	webhooks    *mongo.Collection
+	deliveries  *mongo.Collection
+	deadLetters *mongo.Collection
+	queue       *mongo.Collection
+	SECRET_KEY  string
+}
+
+// InsertWebhook adds a new webhook subscription, encrypting its signing secret
+func (w *webhookRepository) InsertWebhook(ctx context.Context, webhook *domain.Webhook) (*domain.Webhook, error) {
+	encSecret, err := encryptutil.EncryptECB(webhook.Secret, []byte(w.SECRET_KEY))
+	if err != nil {
+		return nil, err
+	}
+
+	stored := *webhook
+	stored.Secret = encSecret
+	stored.CreatedAt = time.Now().UTC()
+
+	res, err := w.webhooks.InsertOne(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	webhook.ID = res.InsertedID.(primitive.ObjectID)
+	webhook.CreatedAt = stored.CreatedAt
+	return webhook, nil
+}
+
+// GetWebhook retrieves a webhook by id with its secret decrypted
+func (w *webhookRepository) GetWebhook(ctx context.Context, id primitive.ObjectID) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	err := w.webhooks.FindOne(ctx, bson.M{"_id": id}).Decode(&webhook)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No webhook found
+		}
+		return nil, err
+	}
+
+	webhook.Secret, _ = encryptutil.DecryptECB(webhook.Secret, []byte(w.SECRET_KEY))
+	return &webhook, nil
+}
+
+// FindWebhooks retrieves all webhooks
+func (w *webhookRepository) FindWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	return w.findWebhooks(ctx, bson.M{})
+}
+
+// FindWebhooksForEvent retrieves the webhooks subscribed to the given event type
+func (w *webhookRepository) FindWebhooksForEvent(ctx context.Context, eventType string) ([]*domain.Webhook, error) {
+	return w.findWebhooks(ctx, bson.M{"events": eventType})
+}
+
+// findWebhooks retrieves the webhooks matching the filter with their secrets decrypted
+func (w *webhookRepository) findWebhooks(ctx context.Context, filter bson.M) ([]*domain.Webhook, error) {
+	var webhooks = make([]*domain.Webhook, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := w.webhooks.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret, _ = encryptutil.DecryptECB(webhook.Secret, []byte(w.SECRET_KEY))
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook subscription
+func (w *webhookRepository) DeleteWebhook(ctx context.Context, id primitive.ObjectID) error {
+	delRes, err := w.webhooks.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if delRes.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// InsertDelivery records a delivery attempt
+func (w *webhookRepository) InsertDelivery(ctx context.Context, delivery *domain.Delivery) error {
+	_, err := w.deliveries.InsertOne(ctx, delivery)
+	return err
+}
+
+// FindDeliveries retrieves the delivery log of a webhook, newest first
+func (w *webhookRepository) FindDeliveries(ctx context.Context, webhookID primitive.ObjectID) ([]*domain.Delivery, error) {
+	var deliveries = make([]*domain.Delivery, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "attemptedAt", Value: -1}}).SetLimit(100)
+	cursor, err := w.deliveries.Find(ctx, bson.M{"webhookId": webhookID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// InsertDeadLetter stores an undeliverable event, encrypting the user it carries
+func (w *webhookRepository) InsertDeadLetter(ctx context.Context, deadLetter *domain.DeadLetter) error {
+	stored := *deadLetter
+	event, err := w.encryptEventUser(&deadLetter.Event)
+	if err != nil {
+		return err
+	}
+	stored.Event = *event
+
+	_, err = w.deadLetters.InsertOne(ctx, stored)
+	return err
+}
+
+// FindDeadLetters retrieves all undeliverable events, newest first
+func (w *webhookRepository) FindDeadLetters(ctx context.Context) ([]*domain.DeadLetter, error) {
+	var deadLetters = make([]*domain.DeadLetter, 0)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "failedAt", Value: -1}})
+	cursor, err := w.deadLetters.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &deadLetters); err != nil {
+		return nil, err
+	}
+
+	for _, deadLetter := range deadLetters {
+		w.decryptEventUser(&deadLetter.Event)
+	}
+	return deadLetters, nil
+}
+
+// TakeDeadLetter removes an undeliverable event from the queue and returns it
+func (w *webhookRepository) TakeDeadLetter(ctx context.Context, id primitive.ObjectID) (*domain.DeadLetter, error) {
+	var deadLetter domain.DeadLetter
+	err := w.deadLetters.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&deadLetter)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil // No dead letter found
+		}
+		return nil, err
+	}
+
+	w.decryptEventUser(&deadLetter.Event)
+	return &deadLetter, nil
+}
+
+// DeleteDeadLettersForUsers removes the undeliverable events carrying one of the users
+func (w *webhookRepository) DeleteDeadLettersForUsers(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := w.deadLetters.DeleteMany(ctx, bson.M{"event.user._id": bson.M{"$in": userIDs}})
+	return err
+}
+
+// EnqueueDeliveries queues the event for delivery to every one of the
+// webhooks, encrypting the user it carries. Queueing an event again for a
+// webhook it is still queued for does nothing, so an event published twice
+// is not delivered twice from the queue.
+func (w *webhookRepository) EnqueueDeliveries(ctx context.Context, webhooks []*domain.Webhook, event *domain.Event) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stored, err := w.encryptEventUser(event)
+	if err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(webhooks))
+	now := time.Now().UTC()
+	for _, webhook := range webhooks {
+		pending := domain.PendingDelivery{WebhookID: webhook.ID, Event: *stored, NextAttemptAt: now, LeasedUntil: now}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"webhookId": webhook.ID, "event.id": event.ID}).
+			SetUpdate(bson.M{"$setOnInsert": pending}).
+			SetUpsert(true))
+	}
+	_, err = w.queue.BulkWrite(ctx, models)
+	return err
+}
+
+// ClaimDeliveries leases up to limit queued deliveries that are due, so no
+// other instance attempts them until the lease runs out. A delivery whose
+// lease ran out without it being rescheduled or completed is due again.
+func (w *webhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*domain.PendingDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	claimed := make([]*domain.PendingDelivery, 0, limit)
+	for len(claimed) < limit {
+		now := time.Now().UTC()
+		filter := bson.M{"nextAttemptAt": bson.M{"$lte": now}, "leasedUntil": bson.M{"$lte": now}}
+		update := bson.M{"$set": bson.M{"leasedUntil": now.Add(lease)}}
+		opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).SetReturnDocument(options.After)
+
+		var pending domain.PendingDelivery
+		err := w.queue.FindOneAndUpdate(ctx, filter, update, opts).Decode(&pending)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return claimed, err
+		}
+		w.decryptEventUser(&pending.Event)
+		claimed = append(claimed, &pending)
+	}
+	return claimed, nil
+}
+
+// RescheduleDelivery records the attempts made on a queued delivery and
+// releases it until the next attempt is due
+func (w *webhookRepository) RescheduleDelivery(ctx context.Context, id primitive.ObjectID, attempts int, next time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := w.queue.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"attempts":      attempts,
+		"nextAttemptAt": next.UTC(),
+		"leasedUntil":   time.Time{},
+	}})
+	return err
+}
+
+// CompleteDelivery removes a delivery from the queue once it was delivered or dead-lettered
+func (w *webhookRepository) CompleteDelivery(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := w.queue.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// DeleteDeliveriesForUsers removes the queued deliveries carrying one of the users
+func (w *webhookRepository) DeleteDeliveriesForUsers(ctx context.Context, userIDs []primitive.ObjectID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	_, err := w.queue.DeleteMany(ctx, bson.M{"event.user._id": bson.M{"$in": userIDs}})
+	return err
+}
+
+// encryptEventUser returns a copy of the event with the user it carries encrypted for storage
+func (w *webhookRepository) encryptEventUser(event *domain.Event) (*domain.Event, error) {
+	stored := *event
+	if event.User == nil {
+		return &stored, nil
+	}
+	user := *event.User
+	var err error
+	if user.Username, err = encryptutil.EncryptECB(user.Username, []byte(w.SECRET_KEY)); err != nil {
+		return nil, err
+	}
+	if user.Phone, err = encryptutil.EncryptECB(user.Phone, []byte(w.SECRET_KEY)); err != nil {
+		return nil, err
+	}
+	stored.User = &user
+	return &stored, nil
+}
+
+// decryptEventUser decrypts the user carried by a stored event
+func (w *webhookRepository) decryptEventUser(event *domain.Event) {
+	if event.User == nil {
+		return
+	}
+	event.User.Username, _ = encryptutil.DecryptECB(event.User.Username, []byte(w.SECRET_KEY))
+	event.User.Phone, _ = encryptutil.DecryptECB(event.User.Phone, []byte(w.SECRET_KEY))
+}
+
+// NewWebhookRepository creates a new webhook repository with collections and secret key.
+// The queue collection holds the deliveries still to be made.
+func NewWebhookRepository(webhooks, deliveries, deadLetters, queue *mongo.Collection, env *bootstrap.Env) WebhooksRepo {
+	return &webhookRepository{
+		webhooks:    webhooks,
+		deliveries:  deliveries,
+		deadLetters: deadLetters,
+		queue:       queue,
+		SECRET_KEY:  env.SECRET_KEY,
+	}
+}
@@ -0,0 +1,98 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/internal/phoneutil"
+	"findApi/repository"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHashes(t *testing.T) {
+	hash := phoneutil.Hash("+15551234567")
+
+	tests := []struct {
+		name    string
+		hashes  []string
+		length  int
+		want    []string
+		wantErr error
+	}{
+		{"none", nil, 64, []string{}, nil},
+		{"hash", []string{hash}, 64, []string{hash}, nil},
+		{"uppercase hash", []string{strings.ToUpper(hash)}, 64, []string{hash}, nil},
+		{"repeated hash", []string{hash, hash}, 64, []string{hash}, nil},
+		{"bucket prefix", []string{hash[:domain.DiscoveryBucketLength]}, domain.DiscoveryBucketLength, []string{hash[:domain.DiscoveryBucketLength]}, nil},
+		{"odd length prefix", []string{"abcde", "01234"}, 5, []string{"abcde", "01234"}, nil},
+		{"too short", []string{hash[:63]}, 64, nil, ErrInvalidHash},
+		{"too long", []string{hash + "0"}, 64, nil, ErrInvalidHash},
+		{"not hex", []string{"abcdg"}, 5, nil, ErrInvalidHash},
+		{"not hex inside", []string{"ab-de"}, 5, nil, ErrInvalidHash},
+		{"empty", []string{""}, 5, nil, ErrInvalidHash},
+		{"one invalid", []string{"abcde", "xyz12"}, 5, nil, ErrInvalidHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeHashes(tt.hashes, tt.length)
+			if !errors.Is(err, tt.wantErr) || !slices.Equal(got, tt.want) {
+				t.Errorf("normalizeHashes(%q, %d) = %q, %v, want %q, %v", tt.hashes, tt.length, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// discoveryUsersRepo answers discovery queries from a fixed set of phone hashes
+type discoveryUsersRepo struct {
+	repository.UsersRepo
+	hashes []string
+}
+
+func (r *discoveryUsersRepo) MatchPhoneHashes(ctx context.Context, hashes []string) ([]string, error) {
+	matches := make([]string, 0)
+	for _, hash := range hashes {
+		if slices.Contains(r.hashes, hash) {
+			matches = append(matches, hash)
+		}
+	}
+	return matches, nil
+}
+
+func (r *discoveryUsersRepo) FindPhoneBuckets(ctx context.Context, prefixes []string) (map[string][]string, error) {
+	buckets := make(map[string][]string, len(prefixes))
+	for _, prefix := range prefixes {
+		buckets[prefix] = make([]string, 0)
+		for _, hash := range r.hashes {
+			if strings.HasPrefix(hash, prefix) {
+				buckets[prefix] = append(buckets[prefix], hash)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+func TestDiscoverContacts(t *testing.T) {
+	known := phoneutil.Hash("+15551234567")
+	unknown := phoneutil.Hash("+15559876543")
+	u := &usersUseCase{repo: &discoveryUsersRepo{hashes: []string{known}}}
+
+	result, err := u.DiscoverContacts(context.Background(), domain.DiscoveryReq{
+		Hashes:   []string{strings.ToUpper(known), unknown},
+		Prefixes: []string{known[:domain.DiscoveryBucketLength]},
+	})
+	if err != nil {
+		t.Fatalf("DiscoverContacts(): %v", err)
+	}
+	if !slices.Equal(result.Matches, []string{known}) {
+		t.Errorf("DiscoverContacts() matches = %q, want %q", result.Matches, []string{known})
+	}
+	if bucket := result.Buckets[known[:domain.DiscoveryBucketLength]]; !slices.Equal(bucket, []string{known}) {
+		t.Errorf("DiscoverContacts() bucket = %q, want %q", bucket, []string{known})
+	}
+
+	if _, err := u.DiscoverContacts(context.Background(), domain.DiscoveryReq{Prefixes: []string{known[:domain.DiscoveryBucketLength+1]}}); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("DiscoverContacts() with a prefix of the wrong length error = %v, want %v", err, ErrInvalidHash)
+	}
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"findApi/domain"
+	"findApi/internal/phoneutil"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of the duplicate signals. Signals are combined so that every
+// matching signal raises the score without it ever exceeding 1.
+const (
+	phoneExactWeight  = 0.9
+	phoneSuffixWeight = 0.75
+	nameWeight        = 0.8
+)
+
+// findDuplicates scores every pair of users and returns the pairs scoring at
+// least minScore, best first. Every pair is compared, which is fine for the
+// size of an address book.
+func findDuplicates(users []*domain.User, minScore float64) []*domain.DuplicateCandidate {
+	candidates := make([]*domain.DuplicateCandidate, 0)
+	for i := 0; i < len(users); i++ {
+		for j := i + 1; j < len(users); j++ {
+			score, reasons := scorePair(users[i], users[j])
+			if score >= minScore && len(reasons) > 0 {
+				candidates = append(candidates, &domain.DuplicateCandidate{
+					First:   users[i],
+					Second:  users[j],
+					Score:   score,
+					Reasons: reasons,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates
+}
+
+// scorePair returns how likely two users describe the same person and why
+func scorePair(a, b *domain.User) (float64, []string) {
+	var reasons []string
+	miss := 1.0
+
+	if exact, likely := phoneutil.SameNumber(a.Phone, b.Phone); exact {
+		miss *= 1 - phoneExactWeight
+		reasons = append(reasons, "same phone")
+	} else if likely {
+		miss *= 1 - phoneSuffixWeight
+		reasons = append(reasons, "similar phone")
+	}
+
+	if similarity := nameSimilarity(a.Username, b.Username); similarity >= 0.7 {
+		miss *= 1 - nameWeight*similarity
+		if similarity == 1 {
+			reasons = append(reasons, "same name")
+		} else {
+			reasons = append(reasons, "similar name")
+		}
+	}
+
+	return 1 - miss, reasons
+}
+
+// nameSimilarity compares two names ignoring case, spacing and punctuation,
+// returning 1 for equal names and 0 for names with nothing in common
+func nameSimilarity(a, b string) float64 {
+	na, nb := normalizeName(a), normalizeName(b)
+	if len(na) == 0 || len(nb) == 0 {
+		return 0
+	}
+
+	longest := max(len(na), len(nb))
+	return 1 - float64(levenshtein(na, nb))/float64(longest)
+}
+
+// normalizeName lowercases a name and drops everything but letters and digits
+func normalizeName(name string) []rune {
+	var normalized []rune
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			normalized = append(normalized, r)
+		}
+	}
+	return normalized
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// resolveMerge picks the username and phone the merged user keeps according
+// to the rules. The merged user joins every group and carries every tag of
+// the duplicates, and is a favorite if any of them was.
+func resolveMerge(primary *domain.User, duplicates []*domain.User, usernameRule, phoneRule string) *domain.User {
+	merged := &domain.User{
+		Username: resolveField(primary, duplicates, usernameRule, func(u *domain.User) string { return u.Username }),
+		Phone:    resolveField(primary, duplicates, phoneRule, func(u *domain.User) string { return u.Phone }),
+	}
+	// The primary takes over the interactions, and with them a newer last contact
+	newest := primary.LastContactedAt
+	for _, duplicate := range duplicates {
+		merged.GroupIDs = append(merged.GroupIDs, duplicate.GroupIDs...)
+		merged.Tags = append(merged.Tags, duplicate.Tags...)
+		merged.Favorite = merged.Favorite || duplicate.Favorite
+		if last := duplicate.LastContactedAt; last != nil && (newest == nil || last.After(*newest)) {
+			newest = last
+			merged.LastContactedAt = last
+		}
+	}
+	return merged
+}
+
+// resolveField picks a single field's value according to a merge conflict rule
+func resolveField(primary *domain.User, duplicates []*domain.User, rule string, field func(*domain.User) string) string {
+	switch rule {
+	case domain.MergeKeepNewest:
+		var newest *domain.User
+		for _, user := range append([]*domain.User{primary}, duplicates...) {
+			if field(user) != "" && (newest == nil || user.ChangeSeq > newest.ChangeSeq) {
+				newest = user
+			}
+		}
+		if newest == nil {
+			return ""
+		}
+		return field(newest)
+	case domain.MergeKeepDuplicate:
+		for _, user := range duplicates {
+			if field(user) != "" {
+				return field(user)
+			}
+		}
+		return field(primary)
+	default:
+		if field(primary) != "" {
+			return field(primary)
+		}
+		for _, user := range duplicates {
+			if field(user) != "" {
+				return field(user)
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"findApi/domain"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScorePair(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        domain.User
+		wantScore   float64
+		wantReasons []string
+	}{
+		{
+			name:        "same phone",
+			a:           domain.User{Username: "ada", Phone: "+1 (555) 123-4567"},
+			b:           domain.User{Username: "grace", Phone: "15551234567"},
+			wantScore:   0.9,
+			wantReasons: []string{"same phone"},
+		},
+		{
+			name:        "phone with and without country code",
+			a:           domain.User{Username: "ada", Phone: "+1 555 123 4567"},
+			b:           domain.User{Username: "grace", Phone: "555-123-4567"},
+			wantScore:   0.75,
+			wantReasons: []string{"similar phone"},
+		},
+		{
+			name:      "phone suffix too short",
+			a:         domain.User{Username: "ada", Phone: "+1 555 123 4567"},
+			b:         domain.User{Username: "grace", Phone: "234567"},
+			wantScore: 0,
+		},
+		{
+			name:        "same name written differently",
+			a:           domain.User{Username: "Ada Lovelace"},
+			b:           domain.User{Username: "ada.lovelace"},
+			wantScore:   0.8,
+			wantReasons: []string{"same name"},
+		},
+		{
+			name:        "similar name",
+			a:           domain.User{Username: "Jonathan"},
+			b:           domain.User{Username: "Johnathan"},
+			wantScore:   0.8 * (1 - 1.0/9),
+			wantReasons: []string{"similar name"},
+		},
+		{
+			name:      "different names",
+			a:         domain.User{Username: "ada"},
+			b:         domain.User{Username: "bob"},
+			wantScore: 0,
+		},
+		{
+			name:        "same phone and name",
+			a:           domain.User{Username: "Ada", Phone: "5551234567"},
+			b:           domain.User{Username: "ada", Phone: "555 123 4567"},
+			wantScore:   1 - (1-0.9)*(1-0.8),
+			wantReasons: []string{"same phone", "same name"},
+		},
+		{
+			name:      "nothing to compare",
+			a:         domain.User{},
+			b:         domain.User{},
+			wantScore: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := scorePair(&tt.a, &tt.b)
+			if math.Abs(score-tt.wantScore) > 1e-9 || !slices.Equal(reasons, tt.wantReasons) {
+				t.Errorf("scorePair() = %v, %q, want %v, %q", score, reasons, tt.wantScore, tt.wantReasons)
+			}
+			// The score does not depend on the order of the pair
+			if reversed, _ := scorePair(&tt.b, &tt.a); math.Abs(reversed-score) > 1e-9 {
+				t.Errorf("scorePair() reversed = %v, want %v", reversed, score)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "abc", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"müller", "muller", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	ada := &domain.User{Username: "Ada Lovelace", Phone: "+44 20 7946 0000"}
+	adaPhone := &domain.User{Username: "Countess", Phone: "+44 20 7946 0000"}
+	adaName := &domain.User{Username: "ada lovelace", Phone: "+1 555 123 4567"}
+	users := []*domain.User{ada, adaPhone, adaName}
+
+	tests := []struct {
+		name     string
+		minScore float64
+		want     [][2]*domain.User
+	}{
+		{"best first", 0.5, [][2]*domain.User{{ada, adaPhone}, {ada, adaName}}},
+		{"below the minimum score", 0.85, [][2]*domain.User{{ada, adaPhone}}},
+		{"every pair with a reason", 0, [][2]*domain.User{{ada, adaPhone}, {ada, adaName}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findDuplicates(users, tt.minScore)
+			if len(got) != len(tt.want) {
+				t.Fatalf("findDuplicates() returned %d candidates, want %d", len(got), len(tt.want))
+			}
+			for i, pair := range tt.want {
+				if got[i].First != pair[0] || got[i].Second != pair[1] {
+					t.Errorf("candidate %d = %s and %s, want %s and %s", i, got[i].First.Username, got[i].Second.Username, pair[0].Username, pair[1].Username)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveMerge(t *testing.T) {
+	earlier := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.AddDate(0, 1, 0)
+	primary := &domain.User{Username: "ada", ChangeSeq: 1, LastContactedAt: &earlier}
+	first := &domain.User{Username: "countess", Phone: "5551234567", ChangeSeq: 3, LastContactedAt: &later}
+	second := &domain.User{Username: "lovelace", Phone: "5559876543", ChangeSeq: 2, Favorite: true}
+	duplicates := []*domain.User{first, second}
+
+	tests := []struct {
+		rule         string
+		wantUsername string
+		wantPhone    string
+	}{
+		{domain.MergeKeepPrimary, "ada", "5551234567"},
+		{domain.MergeKeepDuplicate, "countess", "5551234567"},
+		{domain.MergeKeepNewest, "countess", "5551234567"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			merged := resolveMerge(primary, duplicates, tt.rule, tt.rule)
+			if merged.Username != tt.wantUsername || merged.Phone != tt.wantPhone {
+				t.Errorf("resolveMerge() = %q, %q, want %q, %q", merged.Username, merged.Phone, tt.wantUsername, tt.wantPhone)
+			}
+			if !merged.Favorite {
+				t.Error("resolveMerge() lost the favorite flag of a duplicate")
+			}
+			if merged.LastContactedAt == nil || !merged.LastContactedAt.Equal(later) {
+				t.Errorf("resolveMerge() last contact = %v, want %v", merged.LastContactedAt, later)
+			}
+		})
+	}
+
+	// A primary contacted last keeps its own last contact
+	merged := resolveMerge(&domain.User{Username: "ada", LastContactedAt: &later}, []*domain.User{{Username: "countess", LastContactedAt: &earlier}}, domain.MergeKeepPrimary, domain.MergeKeepPrimary)
+	if merged.LastContactedAt != nil {
+		t.Errorf("resolveMerge() last contact = %v, want the primary's to stay", merged.LastContactedAt)
+	}
+}
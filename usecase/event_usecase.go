@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+)
+
+// ErrEventsExpired is returned when a stream is resumed from an event that is
+// no longer stored, so the events after it cannot all be replayed
+var ErrEventsExpired = errors.New("events expired")
+
+// EventSubscriber hands out live user events as they are published
+type EventSubscriber interface {
+	// Subscribe returns a channel of events and a function that ends the subscription
+	Subscribe(buffer int) (<-chan *domain.Event, func())
+}
+
+// EventsUseCase defines the interface for use case operations for reading user events.
+type EventsUseCase interface {
+	// FindEventsSince retrieves up to limit events of the address book with a
+	// sequence number after seq. It fails with ErrEventsExpired when some of
+	// the events after seq are no longer stored.
+	FindEventsSince(ctx context.Context, addressBook string, seq int64, limit int64) ([]*domain.Event, error)
+
+	// CheckResume fails with ErrEventsExpired when some of the events after seq are no longer stored
+	CheckResume(ctx context.Context, seq int64) error
+}
+
+type eventsUseCase struct {
+	repo repository.OutboxRepo
+}
+
+// NewEventsUseCase creates a new instance of EventsUseCase with the given repository
+func NewEventsUseCase(repo repository.OutboxRepo) EventsUseCase {
+	return &eventsUseCase{
+		repo: repo,
+	}
+}
+
+// FindEventsSince retrieves up to limit events of the address book with a sequence number after seq
+func (e *eventsUseCase) FindEventsSince(ctx context.Context, addressBook string, seq int64, limit int64) ([]*domain.Event, error) {
+	if err := e.CheckResume(ctx, seq); err != nil {
+		return nil, err
+	}
+	return e.repo.FindSince(ctx, addressBook, seq, limit)
+}
+
+// CheckResume fails with ErrEventsExpired when some of the events after seq are no longer stored
+func (e *eventsUseCase) CheckResume(ctx context.Context, seq int64) error {
+	horizon, err := e.repo.ReplayHorizon(ctx)
+	if err != nil {
+		return err
+	}
+	if seq < horizon {
+		return ErrEventsExpired
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"findApi/domain"
+	"findApi/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// GroupsUseCase defines the interface for use case operations for managing groups.
+type GroupsUseCase interface {
+	// CreateGroup adds a new group
+	CreateGroup(ctx context.Context, group *domain.Group) (*domain.Group, error)
+
+	// GetGroup retrieves a group by id with its member count
+	GetGroup(ctx context.Context, id primitive.ObjectID) (*domain.Group, error)
+
+	// FindGroups retrieves all groups with their member counts
+	FindGroups(ctx context.Context) ([]*domain.Group, error)
+
+	// RenameGroup changes the name of a group
+	RenameGroup(ctx context.Context, id primitive.ObjectID, name string) error
+
+	// DeleteGroup removes a group and takes its members out of it
+	DeleteGroup(ctx context.Context, id primitive.ObjectID, actor string) error
+
+	// AddMembers adds users to a group
+	AddMembers(ctx context.Context, id primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error
+
+	// RemoveMember takes a user out of a group
+	RemoveMember(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, actor string) error
+}
+
+type groupsUseCase struct {
+	repo  repository.GroupsRepo
+	users repository.UsersRepo
+}
+
+// NewGroupsUseCase creates a new instance of GroupsUseCase with the given repositories
+func NewGroupsUseCase(repo repository.GroupsRepo, users repository.UsersRepo) GroupsUseCase {
+	return &groupsUseCase{
+		repo:  repo,
+		users: users,
+	}
+}
+
+// CreateGroup adds a new group
+func (g *groupsUseCase) CreateGroup(ctx context.Context, group *domain.Group) (*domain.Group, error) {
+	return g.repo.InsertGroup(ctx, group)
+}
+
+// GetGroup retrieves a group by id with its member count
+func (g *groupsUseCase) GetGroup(ctx context.Context, id primitive.ObjectID) (*domain.Group, error) {
+	group, err := g.repo.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	if group.MemberCount, err = g.users.CountMembers(ctx, group.ID); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// FindGroups retrieves all groups with their member counts
+func (g *groupsUseCase) FindGroups(ctx context.Context) ([]*domain.Group, error) {
+	groups, err := g.repo.FindGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := g.users.CountGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		group.MemberCount = counts[group.ID]
+	}
+	return groups, nil
+}
+
+// RenameGroup changes the name of a group
+func (g *groupsUseCase) RenameGroup(ctx context.Context, id primitive.ObjectID, name string) error {
+	return g.repo.RenameGroup(ctx, id, name)
+}
+
+// DeleteGroup removes a group and takes its members out of it
+func (g *groupsUseCase) DeleteGroup(ctx context.Context, id primitive.ObjectID, actor string) error {
+	// The group goes in the same transaction that takes its members out of it
+	return g.users.RemoveGroup(ctx, id, actor, func(ctx context.Context) error {
+		return g.repo.DeleteGroup(ctx, id)
+	})
+}
+
+// AddMembers adds users to a group
+func (g *groupsUseCase) AddMembers(ctx context.Context, id primitive.ObjectID, userIDs []primitive.ObjectID, actor string) error {
+	group, err := g.repo.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return mongo.ErrNoDocuments
+	}
+	return g.users.AddToGroup(ctx, id, userIDs, actor)
+}
+
+// RemoveMember takes a user out of a group
+func (g *groupsUseCase) RemoveMember(ctx context.Context, id primitive.ObjectID, userID primitive.ObjectID, actor string) error {
+	return g.users.RemoveFromGroup(ctx, id, []primitive.ObjectID{userID}, actor)
+}
@@ -0,0 +1,72 @@
+package usecase
+
+import (
+	"context"
+	"findApi/domain"
+	"sync"
+	"time"
+)
+
+// HealthProbe is a named check of a dependency the application needs to take traffic
+type HealthProbe struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthUseCase defines the interface for use case operations checking the health of the application.
+type HealthUseCase interface {
+	// Ready runs every probe and reports whether the application can take traffic
+	Ready(ctx context.Context) *domain.HealthReport
+}
+
+type healthUseCase struct {
+	probes  []HealthProbe
+	timeout time.Duration
+}
+
+// NewHealthUseCase creates a new instance of HealthUseCase running the given
+// probes, each bounded by the timeout
+func NewHealthUseCase(probes []HealthProbe, timeout time.Duration) HealthUseCase {
+	return &healthUseCase{
+		probes:  probes,
+		timeout: timeout,
+	}
+}
+
+// Ready runs every probe at the same time, so a slow dependency only adds
+// its own timeout, and reports the outcome of each in the order of the probes
+func (h *healthUseCase) Ready(ctx context.Context) *domain.HealthReport {
+	report := &domain.HealthReport{Ready: true, Checks: make([]*domain.HealthCheck, len(h.probes))}
+
+	var wg sync.WaitGroup
+	for i, probe := range h.probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = h.check(ctx, probe)
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != domain.HealthOK {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+// check runs a single probe
+func (h *healthUseCase) check(ctx context.Context, probe HealthProbe) *domain.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := probe.Check(ctx)
+	check := &domain.HealthCheck{Name: probe.Name, Status: domain.HealthOK, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status = domain.HealthFailing
+		check.Error = err.Error()
+	}
+	return check
+}
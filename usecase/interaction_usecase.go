@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrInvalidInteraction is returned for an interaction of an unknown type, in the future or with overlong notes
+	ErrInvalidInteraction = errors.New("invalid interaction")
+
+	// ErrInvalidCursor is returned for a page cursor that does not point into the timeline
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// maxNotesLength is the longest notes, in bytes, an interaction can carry
+const maxNotesLength = 10000
+
+// InteractionsUseCase defines the interface for use case operations for managing the interaction log of users.
+type InteractionsUseCase interface {
+	// AddInteraction records an interaction with a user and updates when the user was last contacted
+	AddInteraction(ctx context.Context, userID primitive.ObjectID, interaction *domain.Interaction, actor string) (*domain.Interaction, error)
+
+	// FindInteractions retrieves a page of the interactions with a user, newest first
+	FindInteractions(ctx context.Context, userID primitive.ObjectID, cursor string, limit int64) (*domain.InteractionPage, error)
+
+	// DeleteInteraction removes an interaction with a user and updates when the user was last contacted
+	DeleteInteraction(ctx context.Context, userID, id primitive.ObjectID, actor string) error
+}
+
+type interactionsUseCase struct {
+	repo  repository.InteractionsRepo
+	users repository.UsersRepo
+}
+
+// NewInteractionsUseCase creates a new instance of InteractionsUseCase with the given repositories
+func NewInteractionsUseCase(repo repository.InteractionsRepo, users repository.UsersRepo) InteractionsUseCase {
+	return &interactionsUseCase{
+		repo:  repo,
+		users: users,
+	}
+}
+
+// AddInteraction records an interaction with a user and updates when the user was last contacted
+func (i *interactionsUseCase) AddInteraction(ctx context.Context, userID primitive.ObjectID, interaction *domain.Interaction, actor string) (*domain.Interaction, error) {
+	now := time.Now().UTC()
+	if interaction.At.IsZero() {
+		interaction.At = now
+	}
+	interaction.At = interaction.At.UTC()
+	if !slices.Contains(domain.InteractionTypes, interaction.Type) || interaction.At.After(now) || len(interaction.Notes) > maxNotesLength {
+		return nil, ErrInvalidInteraction
+	}
+	if err := i.checkActive(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	interaction.UserID = userID
+	created, err := i.repo.InsertInteraction(ctx, interaction)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.refreshLastContacted(ctx, userID, actor); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// FindInteractions retrieves a page of the interactions with a user, newest first
+func (i *interactionsUseCase) FindInteractions(ctx context.Context, userID primitive.ObjectID, cursor string, limit int64) (*domain.InteractionPage, error) {
+	var after *primitive.ObjectID
+	if cursor != "" {
+		id, err := primitive.ObjectIDFromHex(cursor)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		after = &id
+	}
+	if err := i.checkActive(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Read one extra interaction to learn whether there is another page
+	interactions, err := i.repo.FindInteractions(ctx, userID, after, limit+1)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidCursor
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.InteractionPage{Interactions: interactions}
+	if int64(len(interactions)) > limit {
+		page.Interactions = interactions[:limit]
+		page.NextCursor = interactions[limit-1].ID.Hex()
+	}
+	return page, nil
+}
+
+// DeleteInteraction removes an interaction with a user and updates when the user was last contacted
+func (i *interactionsUseCase) DeleteInteraction(ctx context.Context, userID, id primitive.ObjectID, actor string) error {
+	if err := i.checkActive(ctx, userID); err != nil {
+		return err
+	}
+	if err := i.repo.DeleteInteraction(ctx, id, userID); err != nil {
+		return err
+	}
+	return i.refreshLastContacted(ctx, userID, actor)
+}
+
+// refreshLastContacted sets when the user was last contacted from its newest interaction
+func (i *interactionsUseCase) refreshLastContacted(ctx context.Context, userID primitive.ObjectID, actor string) error {
+	latest, err := i.repo.LatestAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return i.users.SetLastContacted(ctx, userID, latest, actor)
+}
+
+// checkActive fails with mongo.ErrNoDocuments unless the user exists outside the trash
+func (i *interactionsUseCase) checkActive(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := i.users.GetUser(ctx, bson.M{"_id": userID})
+	if err != nil {
+		return err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
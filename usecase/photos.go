@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"findApi/domain"
+	"findApi/internal/imageutil"
+	"findApi/repository"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"strconv"
+	"time"
+
+	// Register the decoders of the accepted photo types
+	_ "image/gif"
+	_ "image/png"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrUnsupportedPhoto is returned for a photo that is not a JPEG, PNG or GIF image
+	ErrUnsupportedPhoto = errors.New("unsupported photo type")
+
+	// ErrInvalidPhoto is returned for a photo that cannot be decoded or has too many pixels
+	ErrInvalidPhoto = errors.New("invalid photo")
+
+	// ErrInvalidPhotoSize is returned when asking for a thumbnail size that is not generated
+	ErrInvalidPhotoSize = errors.New("invalid photo size")
+)
+
+// maxPhotoPixels bounds the decoded size of a photo, so a small file cannot
+// expand into a huge image in memory
+const maxPhotoPixels = 4096 * 4096
+
+// photoTypes are the content types accepted as photos, as sniffed from their bytes
+var photoTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// processPhoto checks the photo and renders a JPEG thumbnail for each of the
+// domain.PhotoSizes
+func processPhoto(data []byte) (*domain.Photo, map[int][]byte, error) {
+	contentType := http.DetectContentType(data)
+	if !photoTypes[contentType] {
+		return nil, nil, ErrUnsupportedPhoto
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width*config.Height > maxPhotoPixels {
+		return nil, nil, ErrInvalidPhoto
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrInvalidPhoto
+	}
+
+	thumbnails := make(map[int][]byte, len(domain.PhotoSizes))
+	for _, size := range domain.PhotoSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, imageutil.Thumbnail(img, size), &jpeg.Options{Quality: 85}); err != nil {
+			return nil, nil, err
+		}
+		thumbnails[size] = buf.Bytes()
+	}
+
+	photo := &domain.Photo{
+		ID:          primitive.NewObjectID().Hex(),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       config.Width,
+		Height:      config.Height,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	return photo, thumbnails, nil
+}
+
+// photoBlobDir is the blob directory holding one upload of a user's photo.
+// Every upload gets its own photo id, so replacing a photo never mixes the
+// blobs of two uploads. Photo ids are generated by processPhoto, so one that
+// is not an object id in hex never had blobs stored and fails with
+// repository.ErrBlobNotFound instead of reaching a blob path.
+func photoBlobDir(userID primitive.ObjectID, photoID string) (string, error) {
+	if _, err := primitive.ObjectIDFromHex(photoID); err != nil {
+		return "", repository.ErrBlobNotFound
+	}
+	return userID.Hex() + "/" + photoID, nil
+}
+
+// photoBlobName names the blob of a photo in the given thumbnail size, or of
+// the original image when size is 0
+func photoBlobName(userID primitive.ObjectID, photoID string, size int) (string, error) {
+	dir, err := photoBlobDir(userID, photoID)
+	if err != nil {
+		return "", err
+	}
+	return photoBlobIn(dir, size), nil
+}
+
+// photoBlobIn names the blob of a photo size within its photoBlobDir
+func photoBlobIn(dir string, size int) string {
+	if size == 0 {
+		return dir + "/original"
+	}
+	return dir + "/" + strconv.Itoa(size)
+}
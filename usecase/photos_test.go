@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"errors"
+	"findApi/repository"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPhotoBlobName(t *testing.T) {
+	userID := primitive.NewObjectID()
+	photoID := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name    string
+		photoID string
+		size    int
+		want    string
+		wantErr error
+	}{
+		{"original", photoID, 0, userID.Hex() + "/" + photoID + "/original", nil},
+		{"thumbnail", photoID, 128, userID.Hex() + "/" + photoID + "/128", nil},
+		{"empty photo id", "", 0, "", repository.ErrBlobNotFound},
+		{"parent directory", "..", 0, "", repository.ErrBlobNotFound},
+		{"another user's photo", "../" + primitive.NewObjectID().Hex() + "/" + photoID, 0, "", repository.ErrBlobNotFound},
+		{"not an object id", "photo", 0, "", repository.ErrBlobNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := photoBlobName(userID, tt.photoID, tt.size)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("photoBlobName(%q, %d) = %q, %v, want %q, %v", tt.photoID, tt.size, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidSubject is returned for a data subject request naming neither a username nor a phone
+var ErrInvalidSubject = errors.New("invalid subject")
+
+// exportPageSize is how many interactions are read at a time for an export
+const exportPageSize = 500
+
+// PrivacyUseCase defines the interface for use case operations answering data subject requests.
+type PrivacyUseCase interface {
+	// ExportSubject collects everything kept about the users matching the subject
+	ExportSubject(ctx context.Context, subject *domain.SubjectReq) (*domain.SubjectExport, error)
+
+	// EraseSubject erases the users matching the subject and everything kept about them
+	EraseSubject(ctx context.Context, subject *domain.SubjectReq) (*domain.ErasureReport, error)
+}
+
+type privacyUseCase struct {
+	users         repository.UsersRepo
+	interactions  repository.InteractionsRepo
+	relationships repository.RelationshipsRepo
+	groups        repository.GroupsRepo
+	reminders     repository.RemindersRepo
+	webhooks      repository.WebhooksRepo
+	photos        repository.BlobStore
+
+	// backupRetention is how long backups are kept, and so how long an
+	// erased user may still be restored from one
+	backupRetention time.Duration
+}
+
+// NewPrivacyUseCase creates a new instance of PrivacyUseCase with the given repositories
+// and the time backups are kept for
+func NewPrivacyUseCase(users repository.UsersRepo, interactions repository.InteractionsRepo, relationships repository.RelationshipsRepo, groups repository.GroupsRepo, reminders repository.RemindersRepo, webhooks repository.WebhooksRepo, photos repository.BlobStore, backupRetention time.Duration) PrivacyUseCase {
+	return &privacyUseCase{
+		users:         users,
+		interactions:  interactions,
+		relationships: relationships,
+		groups:        groups,
+		reminders:     reminders,
+		webhooks:      webhooks,
+		photos:        photos,
+
+		backupRetention: backupRetention,
+	}
+}
+
+// ExportSubject collects everything kept about the users matching the
+// subject, in or out of the trash. It fails with mongo.ErrNoDocuments when
+// no user matches.
+func (p *privacyUseCase) ExportSubject(ctx context.Context, subject *domain.SubjectReq) (*domain.SubjectExport, error) {
+	users, err := p.findSubject(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &domain.SubjectExport{ExportedAt: time.Now().UTC(), Subject: *subject}
+	for _, user := range users {
+		record, err := p.exportUser(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		export.Records = append(export.Records, record)
+	}
+	return export, nil
+}
+
+// exportUser collects everything kept about one user
+func (p *privacyUseCase) exportUser(ctx context.Context, user *domain.User) (*domain.SubjectRecord, error) {
+	record := &domain.SubjectRecord{User: user, Interactions: make([]*domain.Interaction, 0), Groups: make([]*domain.Group, 0)}
+
+	var err error
+	if record.Revisions, err = p.users.FindRevisions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if record.Relationships, err = p.relationships.FindForUsers(ctx, []primitive.ObjectID{user.ID}); err != nil {
+		return nil, err
+	}
+
+	// Read the whole timeline, page by page
+	var after *primitive.ObjectID
+	for {
+		page, err := p.interactions.FindInteractions(ctx, user.ID, after, exportPageSize)
+		if err != nil {
+			return nil, err
+		}
+		record.Interactions = append(record.Interactions, page...)
+		if len(page) < exportPageSize {
+			break
+		}
+		after = &page[len(page)-1].ID
+	}
+
+	for _, groupID := range user.GroupIDs {
+		group, err := p.groups.GetGroup(ctx, groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group != nil {
+			record.Groups = append(record.Groups, group)
+		}
+	}
+
+	if user.Photo != nil {
+		name, err := photoBlobName(user.ID, user.Photo.ID, 0)
+		if err == nil {
+			record.Photo, err = p.photos.Get(ctx, name)
+		}
+		if err != nil && !errors.Is(err, repository.ErrBlobNotFound) {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// EraseSubject erases the users matching the subject, in or out of the
+// trash. The users are replaced with tombstones and their keys destroyed
+// first, so their revisions and interaction notes are unreadable even where
+// the removal of the rest fails or a backup holds a copy. Backups still hold
+// the usernames and phones under the shared secret, so the erasure only
+// completes once they have expired, as reported. It fails with
+// mongo.ErrNoDocuments when no user matches.
+func (p *privacyUseCase) EraseSubject(ctx context.Context, subject *domain.SubjectReq) (*domain.ErasureReport, error) {
+	users, err := p.findSubject(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	if err := p.users.EraseUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	// Once the users are erased, the rest is removed even when the caller goes away meanwhile
+	ctx = context.WithoutCancel(ctx)
+	if err := p.relationships.DeleteForUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := p.interactions.DeleteForUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := p.reminders.DeleteForUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := p.webhooks.DeleteDeadLettersForUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	if err := p.webhooks.DeleteDeliveriesForUsers(ctx, ids); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err := p.photos.Delete(ctx, id.Hex()); err != nil {
+			return nil, err
+		}
+	}
+
+	erasedAt := time.Now().UTC()
+	slog.InfoContext(ctx, "Erased users", "users", ids)
+	return &domain.ErasureReport{ErasedAt: erasedAt, CompletesAt: erasedAt.Add(p.backupRetention), UserIDs: ids}, nil
+}
+
+// findSubject retrieves the users matching the subject, failing with
+// mongo.ErrNoDocuments when there are none
+func (p *privacyUseCase) findSubject(ctx context.Context, subject *domain.SubjectReq) ([]*domain.User, error) {
+	if subject.Username == "" && subject.Phone == "" {
+		return nil, ErrInvalidSubject
+	}
+	users, err := p.users.FindSubject(ctx, subject.Username, subject.Phone)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return users, nil
+}
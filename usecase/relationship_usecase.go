@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidRelationship is returned for an unknown relationship type or a user related to itself
+var ErrInvalidRelationship = errors.New("invalid relationship")
+
+// maxRelationshipDepth bounds how many edges a traversal follows
+const maxRelationshipDepth = 10
+
+// RelationshipsUseCase defines the interface for use case operations for managing relationships between users.
+type RelationshipsUseCase interface {
+	// AddRelationship records that the user to is related to the user, and the reverse edge when bidirectional
+	AddRelationship(ctx context.Context, id, to primitive.ObjectID, relType string, bidirectional bool) ([]*domain.Relationship, error)
+
+	// FindRelationships retrieves the relationships of a user, of one type or of any type when relType is empty
+	FindRelationships(ctx context.Context, id primitive.ObjectID, relType string) ([]*domain.Relationship, error)
+
+	// DeleteRelationship removes a relationship of a user, and the reverse edge when bidirectional
+	DeleteRelationship(ctx context.Context, id, relationshipID primitive.ObjectID, bidirectional bool) error
+
+	// FindRelated follows relationships from a user up to depth edges away
+	FindRelated(ctx context.Context, id primitive.ObjectID, relType string, depth int) ([]*domain.RelatedUser, error)
+}
+
+type relationshipsUseCase struct {
+	repo  repository.RelationshipsRepo
+	users repository.UsersRepo
+}
+
+// NewRelationshipsUseCase creates a new instance of RelationshipsUseCase with the given repositories
+func NewRelationshipsUseCase(repo repository.RelationshipsRepo, users repository.UsersRepo) RelationshipsUseCase {
+	return &relationshipsUseCase{
+		repo:  repo,
+		users: users,
+	}
+}
+
+// AddRelationship records that the user to is related to the user, and the reverse edge when bidirectional
+func (r *relationshipsUseCase) AddRelationship(ctx context.Context, id, to primitive.ObjectID, relType string, bidirectional bool) ([]*domain.Relationship, error) {
+	inverse, ok := domain.RelationInverses[relType]
+	if !ok || id == to {
+		return nil, ErrInvalidRelationship
+	}
+	if err := r.checkActive(ctx, id, to); err != nil {
+		return nil, err
+	}
+
+	relationship, err := r.repo.UpsertRelationship(ctx, &domain.Relationship{From: id, To: to, Type: relType})
+	if err != nil {
+		return nil, err
+	}
+	relationships := []*domain.Relationship{relationship}
+	if bidirectional {
+		reverse, err := r.repo.UpsertRelationship(ctx, &domain.Relationship{From: to, To: id, Type: inverse})
+		if err != nil {
+			return nil, err
+		}
+		relationships = append(relationships, reverse)
+	}
+	return relationships, nil
+}
+
+// FindRelationships retrieves the relationships of a user, of one type or of any type when relType is empty
+func (r *relationshipsUseCase) FindRelationships(ctx context.Context, id primitive.ObjectID, relType string) ([]*domain.Relationship, error) {
+	if _, ok := domain.RelationInverses[relType]; relType != "" && !ok {
+		return nil, ErrInvalidRelationship
+	}
+	if err := r.checkActive(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.repo.FindRelationships(ctx, []primitive.ObjectID{id}, relType)
+}
+
+// DeleteRelationship removes a relationship of a user, and the reverse edge when bidirectional
+func (r *relationshipsUseCase) DeleteRelationship(ctx context.Context, id, relationshipID primitive.ObjectID, bidirectional bool) error {
+	relationship, err := r.repo.DeleteRelationship(ctx, relationshipID, id)
+	if err != nil {
+		return err
+	}
+	if bidirectional {
+		return r.repo.DeleteEdge(ctx, relationship.To, relationship.From, domain.RelationInverses[relationship.Type])
+	}
+	return nil
+}
+
+// FindRelated follows relationships from a user up to depth edges away,
+// breadth first, listing every user once at the depth it was first reached
+func (r *relationshipsUseCase) FindRelated(ctx context.Context, id primitive.ObjectID, relType string, depth int) ([]*domain.RelatedUser, error) {
+	if _, ok := domain.RelationInverses[relType]; relType != "" && !ok {
+		return nil, ErrInvalidRelationship
+	}
+	if err := r.checkActive(ctx, id); err != nil {
+		return nil, err
+	}
+	depth = min(depth, maxRelationshipDepth)
+
+	related := make([]*domain.RelatedUser, 0)
+	visited := map[primitive.ObjectID]bool{id: true}
+	frontier := []primitive.ObjectID{id}
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		edges, err := r.repo.FindRelationships(ctx, frontier, relType)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for _, edge := range edges {
+			if visited[edge.To] {
+				continue
+			}
+			visited[edge.To] = true
+			related = append(related, &domain.RelatedUser{UserID: edge.To, Type: edge.Type, Of: edge.From, Depth: level})
+			frontier = append(frontier, edge.To)
+		}
+	}
+	return related, nil
+}
+
+// checkActive fails with mongo.ErrNoDocuments unless every user exists outside the trash
+func (r *relationshipsUseCase) checkActive(ctx context.Context, ids ...primitive.ObjectID) error {
+	for _, id := range ids {
+		user, err := r.users.GetUser(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		if user == nil || user.DeletedAt != nil {
+			return mongo.ErrNoDocuments
+		}
+	}
+	return nil
+}
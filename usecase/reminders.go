@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"errors"
+	"findApi/domain"
+	"sort"
+	"time"
+)
+
+// ErrInvalidDate is returned for a birthday or anniversary that is not a real date
+var ErrInvalidDate = errors.New("invalid date")
+
+// validateDate checks that the date exists and, when it has a year, is not in
+// the future. Without a year February 29 is accepted.
+func validateDate(date *domain.Date, now time.Time) error {
+	if date == nil {
+		return nil
+	}
+	year := date.Year
+	if year == 0 {
+		year = 2000 // a leap year
+	}
+	t := time.Date(year, time.Month(date.Month), date.Day, 0, 0, 0, 0, time.UTC)
+	if date.Month < 1 || date.Month > 12 || t.Month() != time.Month(date.Month) || t.Day() != date.Day {
+		return ErrInvalidDate
+	}
+	if date.Year < 0 || (date.Year != 0 && t.After(now)) {
+		return ErrInvalidDate
+	}
+	return nil
+}
+
+// validateDates checks the birthday and anniversary of a new user, dropping
+// dates sent without a month
+func validateDates(user *domain.User) error {
+	now := time.Now()
+	if user.Birthday != nil && user.Birthday.Month == 0 {
+		user.Birthday = nil
+	}
+	if user.Anniversary != nil && user.Anniversary.Month == 0 {
+		user.Anniversary = nil
+	}
+	if err := validateDate(user.Birthday, now); err != nil {
+		return err
+	}
+	return validateDate(user.Anniversary, now)
+}
+
+// startOfDay is midnight of the day of t, in the time zone of t
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextOccurrence is the first day on or after today on which the yearly date
+// falls. In years without February 29 that date is celebrated on February 28.
+func nextOccurrence(date *domain.Date, today time.Time) time.Time {
+	for year := today.Year(); ; year++ {
+		day := date.Day
+		if date.Month == 2 && day == 29 && !isLeapYear(year) {
+			day = 28
+		}
+		occurrence := time.Date(year, time.Month(date.Month), day, 0, 0, 0, 0, today.Location())
+		if !occurrence.Before(today) {
+			return occurrence
+		}
+	}
+}
+
+// isLeapYear reports whether the year has a February 29
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// daysBetween counts the calendar days from one midnight to another. It
+// compares dates rather than durations, so days that are 23 or 25 hours long
+// because of daylight saving time still count as one.
+func daysBetween(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// upcomingReminders lists the birthdays and anniversaries of the users that
+// fall within days of today, soonest first. Today is the day of now in the
+// time zone of now.
+func upcomingReminders(users []*domain.User, now time.Time, days int) []*domain.Reminder {
+	today := startOfDay(now)
+	reminders := make([]*domain.Reminder, 0)
+	for _, user := range users {
+		for kind, date := range map[string]*domain.Date{
+			domain.ReminderBirthday:    user.Birthday,
+			domain.ReminderAnniversary: user.Anniversary,
+		} {
+			if date == nil {
+				continue
+			}
+			occurrence := nextOccurrence(date, today)
+			daysUntil := daysBetween(today, occurrence)
+			if daysUntil > days {
+				continue
+			}
+
+			reminder := &domain.Reminder{
+				UserID:    user.ID.Hex(),
+				Username:  user.Username,
+				Kind:      kind,
+				Date:      occurrence.Format(time.DateOnly),
+				DaysUntil: daysUntil,
+			}
+			if date.Year != 0 {
+				reminder.Years = occurrence.Year() - date.Year
+			}
+			reminders = append(reminders, reminder)
+		}
+	}
+
+	sort.Slice(reminders, func(i, j int) bool {
+		if reminders[i].DaysUntil != reminders[j].DaysUntil {
+			return reminders[i].DaysUntil < reminders[j].DaysUntil
+		}
+		if reminders[i].Username != reminders[j].Username {
+			return reminders[i].Username < reminders[j].Username
+		}
+		return reminders[i].Kind < reminders[j].Kind
+	})
+	return reminders
+}
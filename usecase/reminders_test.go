@@ -0,0 +1,267 @@
+package usecase
+
+import (
+	"errors"
+	"findApi/domain"
+	"testing"
+	"time"
+
+	// Embed the time zone database, so the tests do not depend on the host's
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return location
+}
+
+func TestValidateDate(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		date *domain.Date
+		want error
+	}{
+		{"no date", nil, nil},
+		{"with year", &domain.Date{Year: 1990, Month: 7, Day: 14}, nil},
+		{"without year", &domain.Date{Month: 12, Day: 31}, nil},
+		{"february 29 without year", &domain.Date{Month: 2, Day: 29}, nil},
+		{"february 29 in a leap year", &domain.Date{Year: 2024, Month: 2, Day: 29}, nil},
+		{"february 29 in a common year", &domain.Date{Year: 2023, Month: 2, Day: 29}, ErrInvalidDate},
+		{"february 29 in a century year", &domain.Date{Year: 1900, Month: 2, Day: 29}, ErrInvalidDate},
+		{"february 29 in a leap century year", &domain.Date{Year: 2000, Month: 2, Day: 29}, nil},
+		{"april 31", &domain.Date{Month: 4, Day: 31}, ErrInvalidDate},
+		{"day 0", &domain.Date{Month: 4, Day: 0}, ErrInvalidDate},
+		{"month 0", &domain.Date{Month: 0, Day: 1}, ErrInvalidDate},
+		{"month 13", &domain.Date{Month: 13, Day: 1}, ErrInvalidDate},
+		{"today", &domain.Date{Year: 2025, Month: 6, Day: 1}, nil},
+		{"tomorrow", &domain.Date{Year: 2025, Month: 6, Day: 2}, ErrInvalidDate},
+		{"negative year", &domain.Date{Year: -1, Month: 1, Day: 1}, ErrInvalidDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateDate(tt.date, now); !errors.Is(err, tt.want) {
+				t.Errorf("validateDate(%+v) = %v, want %v", tt.date, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	auckland := mustLoadLocation(t, "Pacific/Auckland")
+
+	tests := []struct {
+		name  string
+		date  domain.Date
+		today time.Time
+		want  time.Time
+	}{
+		{
+			name:  "later this year",
+			date:  domain.Date{Month: 7, Day: 14},
+			today: time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.July, 14, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "today",
+			date:  domain.Date{Year: 1990, Month: 3, Day: 10},
+			today: time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "passed this year",
+			date:  domain.Date{Month: 1, Day: 5},
+			today: time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, time.January, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "new year's eve",
+			date:  domain.Date{Month: 12, Day: 31},
+			today: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "february 29 in a leap year",
+			date:  domain.Date{Month: 2, Day: 29},
+			today: time.Date(2028, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "february 29 in a common year",
+			date:  domain.Date{Month: 2, Day: 29},
+			today: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "february 29 on february 28 of a common year",
+			date:  domain.Date{Month: 2, Day: 29},
+			today: time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "february 29 passed in a common year",
+			date:  domain.Date{Month: 2, Day: 29},
+			today: time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "february 29 in a century year",
+			date:  domain.Date{Month: 2, Day: 29},
+			today: time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2100, time.February, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "in the time zone of today",
+			date:  domain.Date{Month: 4, Day: 6},
+			today: time.Date(2025, time.April, 6, 0, 0, 0, 0, auckland),
+			want:  time.Date(2025, time.April, 6, 0, 0, 0, 0, auckland),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextOccurrence(&tt.date, tt.today)
+			if !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+				t.Errorf("nextOccurrence(%+v, %v) = %v, want %v", tt.date, tt.today, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	auckland := mustLoadLocation(t, "Pacific/Auckland")
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int
+	}{
+		{
+			name: "same day",
+			from: time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "over new year",
+			from: time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want: 1,
+		},
+		{
+			name: "over february 29",
+			from: time.Date(2024, time.February, 28, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: 2,
+		},
+		{
+			name: "a whole leap year",
+			from: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want: 366,
+		},
+		{
+			// 47 hours apart, the clocks went forward
+			name: "over the start of daylight saving time",
+			from: time.Date(2025, time.March, 8, 0, 0, 0, 0, newYork),
+			to:   time.Date(2025, time.March, 10, 0, 0, 0, 0, newYork),
+			want: 2,
+		},
+		{
+			// 49 hours apart, the clocks went back
+			name: "over the end of daylight saving time",
+			from: time.Date(2025, time.November, 1, 0, 0, 0, 0, newYork),
+			to:   time.Date(2025, time.November, 3, 0, 0, 0, 0, newYork),
+			want: 2,
+		},
+		{
+			name: "over the end of daylight saving time in the southern hemisphere",
+			from: time.Date(2025, time.April, 5, 0, 0, 0, 0, auckland),
+			to:   time.Date(2025, time.April, 6, 0, 0, 0, 0, auckland),
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daysBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("daysBetween(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpcomingReminders(t *testing.T) {
+	losAngeles := mustLoadLocation(t, "America/Los_Angeles")
+	user := &domain.User{
+		Username:    "ada",
+		Birthday:    &domain.Date{Year: 1990, Month: 3, Day: 10},
+		Anniversary: &domain.Date{Year: 2020, Month: 2, Day: 29},
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		days int
+		want []domain.Reminder
+	}{
+		{
+			name: "on the day",
+			now:  time.Date(2025, time.March, 10, 8, 0, 0, 0, time.UTC),
+			days: 0,
+			want: []domain.Reminder{
+				{Username: "ada", Kind: domain.ReminderBirthday, Date: "2025-03-10", DaysUntil: 0, Years: 35},
+			},
+		},
+		{
+			// Already March 10 in UTC, but still the evening before in Los Angeles
+			name: "the day before in the time zone of now",
+			now:  time.Date(2025, time.March, 9, 23, 30, 0, 0, losAngeles),
+			days: 1,
+			want: []domain.Reminder{
+				{Username: "ada", Kind: domain.ReminderBirthday, Date: "2025-03-10", DaysUntil: 1, Years: 35},
+			},
+		},
+		{
+			name: "february 29 on february 28 of a common year",
+			now:  time.Date(2025, time.February, 27, 12, 0, 0, 0, time.UTC),
+			days: 1,
+			want: []domain.Reminder{
+				{Username: "ada", Kind: domain.ReminderAnniversary, Date: "2025-02-28", DaysUntil: 1, Years: 5},
+			},
+		},
+		{
+			name: "soonest first",
+			now:  time.Date(2025, time.February, 20, 12, 0, 0, 0, time.UTC),
+			days: 30,
+			want: []domain.Reminder{
+				{Username: "ada", Kind: domain.ReminderAnniversary, Date: "2025-02-28", DaysUntil: 8, Years: 5},
+				{Username: "ada", Kind: domain.ReminderBirthday, Date: "2025-03-10", DaysUntil: 18, Years: 35},
+			},
+		},
+		{
+			name: "none within the days",
+			now:  time.Date(2025, time.April, 1, 12, 0, 0, 0, time.UTC),
+			days: 30,
+			want: []domain.Reminder{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upcomingReminders([]*domain.User{user}, tt.now, tt.days)
+			if len(got) != len(tt.want) {
+				t.Fatalf("upcomingReminders() returned %d reminders, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				want.UserID = user.ID.Hex()
+				if *got[i] != want {
+					t.Errorf("reminder %d = %+v, want %+v", i, *got[i], want)
+				}
+			}
+		})
+	}
+}
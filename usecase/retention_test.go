@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"findApi/domain"
+	"findApi/repository"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// retentionUsersRepo holds users in address books, each with one stale
+// user, one trashed user and one revision
+type retentionUsersRepo struct {
+	repository.UsersRepo
+	addressBooks []string
+}
+
+// count returns how many of the address books are in the scope
+func (r *retentionUsersRepo) count(scope domain.RetentionScope) int64 {
+	var count int64
+	for _, addressBook := range r.addressBooks {
+		if scope.AddressBook == addressBook || (scope.AddressBook == "" && !slices.Contains(scope.Excluded, addressBook)) {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *retentionUsersRepo) FindStale(ctx context.Context, scope domain.RetentionScope, before time.Time) ([]primitive.ObjectID, error) {
+	return make([]primitive.ObjectID, r.count(scope)), nil
+}
+
+func (r *retentionUsersRepo) CountTrash(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error) {
+	return r.count(scope), nil
+}
+
+func (r *retentionUsersRepo) CountRevisions(ctx context.Context, scope domain.RetentionScope, before time.Time) (int64, error) {
+	return r.count(scope), nil
+}
+
+// retentionPoliciesRepo keeps the policies of address books in memory
+type retentionPoliciesRepo struct {
+	repository.RetentionPoliciesRepo
+	policies []*domain.RetentionPolicy
+}
+
+func (r *retentionPoliciesRepo) FindPolicies(ctx context.Context) ([]*domain.RetentionPolicy, error) {
+	return r.policies, nil
+}
+
+func (r *retentionPoliciesRepo) GetPolicy(ctx context.Context, addressBook string) (*domain.RetentionPolicy, error) {
+	for _, policy := range r.policies {
+		if policy.AddressBook == addressBook {
+			return policy, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestApplyPoliciesPerAddressBook(t *testing.T) {
+	const day = 24 * time.Hour
+	users := &retentionUsersRepo{addressBooks: []string{domain.DefaultAddressBook, "family", "work"}}
+	policies := &retentionPoliciesRepo{policies: []*domain.RetentionPolicy{
+		{AddressBook: "work", Trash: 7 * day},
+	}}
+	defaults := domain.RetentionPolicy{StaleUsers: 365 * day, Trash: 30 * day}
+	r := NewRetentionUseCase(users, nil, policies, defaults)
+
+	type result struct {
+		addressBook string
+		rule        string
+		retention   time.Duration
+		affected    int64
+	}
+	check := func(t *testing.T, report *domain.RetentionReport, want []result) {
+		t.Helper()
+		if len(report.Results) != len(want) {
+			t.Fatalf("report has %d results, want %d", len(report.Results), len(want))
+		}
+		for i, want := range want {
+			got := report.Results[i]
+			// The cutoff is taken shortly after the report starts
+			age := report.StartedAt.Sub(got.Cutoff)
+			if got.AddressBook != want.addressBook || got.Rule != want.rule || got.Affected != want.affected || age > want.retention || age < want.retention-time.Minute {
+				t.Errorf("result %d = %q %s %d at %v, want %q %s %d at %v", i, got.AddressBook, got.Rule, got.Affected, age, want.addressBook, want.rule, want.affected, want.retention)
+			}
+		}
+	}
+
+	t.Run("every address book", func(t *testing.T) {
+		report, err := r.ApplyPolicies(context.Background(), true)
+		if err != nil {
+			t.Fatalf("ApplyPolicies(): %v", err)
+		}
+		// The work address book is kept under its own policy, the others under the default one
+		check(t, report, []result{
+			{"work", domain.RetentionTrash, 7 * day, 1},
+			{"", domain.RetentionStaleUsers, 365 * day, 2},
+			{"", domain.RetentionTrash, 30 * day, 2},
+		})
+	})
+
+	t.Run("address book with its own policy", func(t *testing.T) {
+		report, err := r.PreviewPolicy(context.Background(), "work")
+		if err != nil {
+			t.Fatalf("PreviewPolicy(): %v", err)
+		}
+		check(t, report, []result{{"work", domain.RetentionTrash, 7 * day, 1}})
+	})
+
+	t.Run("address book under the default policy", func(t *testing.T) {
+		report, err := r.PreviewPolicy(context.Background(), "family")
+		if err != nil {
+			t.Fatalf("PreviewPolicy(): %v", err)
+		}
+		check(t, report, []result{
+			{"", domain.RetentionStaleUsers, 365 * day, 1},
+			{"", domain.RetentionTrash, 30 * day, 1},
+		})
+	})
+}
+
+func TestRetentionDays(t *testing.T) {
+	days := domain.RetentionDays{StaleUsersDays: 1825, TrashDays: 30, RevisionsDays: 0.5}
+	policy := days.Policy("work")
+	want := domain.RetentionPolicy{AddressBook: "work", StaleUsers: 1825 * 24 * time.Hour, Trash: 30 * 24 * time.Hour, Revisions: 12 * time.Hour}
+	if policy != want {
+		t.Errorf("Policy() = %+v, want %+v", policy, want)
+	}
+
+	days.AddressBook = "work"
+	if got := policy.Days(); got != days {
+		t.Errorf("Days() = %+v, want %+v", got, days)
+	}
+	if got := (domain.RetentionPolicy{Trash: time.Hour}).Days(); !got.Default {
+		t.Errorf("Days() of the default policy = %+v, want it marked default", got)
+	}
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RetentionUseCase defines the interface for use case operations applying data retention rules.
+type RetentionUseCase interface {
+	// ApplyPolicies runs every enabled rule of every address book, under its own policy or the
+	// default one, or only reports what they would remove in a dry run
+	ApplyPolicies(ctx context.Context, dryRun bool) (*domain.RetentionReport, error)
+
+	// PreviewPolicy reports what the rules of an address book would remove now, without removing anything
+	PreviewPolicy(ctx context.Context, addressBook string) (*domain.RetentionReport, error)
+
+	// GetPolicy retrieves the policy of an address book, or the default one when it has none
+	GetPolicy(ctx context.Context, addressBook string) (*domain.RetentionPolicy, error)
+
+	// SetPolicy gives an address book a policy of its own
+	SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error
+
+	// DeletePolicy removes the policy of an address book, which falls back to the default one
+	DeletePolicy(ctx context.Context, addressBook string) error
+}
+
+type retentionUseCase struct {
+	users       repository.UsersRepo
+	userUsecase UsersUseCase
+	policies    repository.RetentionPoliciesRepo
+	defaults    domain.RetentionPolicy
+}
+
+// NewRetentionUseCase creates a new instance of RetentionUseCase. Address
+// books without a policy of their own are kept under the defaults. Purging
+// the trash goes through the users use case, which also removes what is kept
+// about the purged users.
+func NewRetentionUseCase(users repository.UsersRepo, userUsecase UsersUseCase, policies repository.RetentionPoliciesRepo, defaults domain.RetentionPolicy) RetentionUseCase {
+	defaults.AddressBook = ""
+	return &retentionUseCase{
+		users:       users,
+		userUsecase: userUsecase,
+		policies:    policies,
+		defaults:    defaults,
+	}
+}
+
+// ApplyPolicies runs every enabled rule of every address book, or only
+// reports what they would remove in a dry run. Each address book with a
+// policy of its own is kept under it; the default policy covers all others.
+func (r *retentionUseCase) ApplyPolicies(ctx context.Context, dryRun bool) (*domain.RetentionReport, error) {
+	report := &domain.RetentionReport{DryRun: dryRun, StartedAt: time.Now().UTC(), Results: make([]*domain.RetentionResult, 0)}
+
+	policies, err := r.policies.FindPolicies(ctx)
+	if err != nil {
+		return report, err
+	}
+	excluded := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		excluded = append(excluded, policy.AddressBook)
+		if err := r.apply(ctx, report, *policy, domain.RetentionScope{AddressBook: policy.AddressBook}); err != nil {
+			return report, err
+		}
+	}
+	if err := r.apply(ctx, report, r.defaults, domain.RetentionScope{Excluded: excluded}); err != nil {
+		return report, err
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+// PreviewPolicy reports what the rules of an address book would remove now
+func (r *retentionUseCase) PreviewPolicy(ctx context.Context, addressBook string) (*domain.RetentionReport, error) {
+	report := &domain.RetentionReport{DryRun: true, StartedAt: time.Now().UTC(), Results: make([]*domain.RetentionResult, 0)}
+
+	policy, err := r.GetPolicy(ctx, addressBook)
+	if err != nil {
+		return report, err
+	}
+	if err := r.apply(ctx, report, *policy, domain.RetentionScope{AddressBook: addressBook}); err != nil {
+		return report, err
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+// GetPolicy retrieves the policy of an address book, or the default one when it has none
+func (r *retentionUseCase) GetPolicy(ctx context.Context, addressBook string) (*domain.RetentionPolicy, error) {
+	policy, err := r.policies.GetPolicy(ctx, addressBook)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		defaults := r.defaults
+		return &defaults, nil
+	}
+	return policy, nil
+}
+
+// SetPolicy gives an address book a policy of its own
+func (r *retentionUseCase) SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error {
+	return r.policies.SetPolicy(ctx, policy)
+}
+
+// DeletePolicy removes the policy of an address book
+func (r *retentionUseCase) DeletePolicy(ctx context.Context, addressBook string) error {
+	return r.policies.DeletePolicy(ctx, addressBook)
+}
+
+// apply runs every enabled rule of the policy on the users in the scope and
+// adds their results to the report. Stale users are moved to the trash, so
+// they can still be restored until the trash rule purges them.
+func (r *retentionUseCase) apply(ctx context.Context, report *domain.RetentionReport, policy domain.RetentionPolicy, scope domain.RetentionScope) error {
+	rules := []struct {
+		name      string
+		retention time.Duration
+		apply     func(cutoff time.Time) (int64, error)
+	}{
+		{domain.RetentionStaleUsers, policy.StaleUsers, func(cutoff time.Time) (int64, error) {
+			return r.trashStale(ctx, scope, cutoff, report.DryRun)
+		}},
+		{domain.RetentionTrash, policy.Trash, func(cutoff time.Time) (int64, error) {
+			if report.DryRun {
+				return r.users.CountTrash(ctx, scope, cutoff)
+			}
+			return r.userUsecase.PurgeTrash(ctx, scope, policy.Trash)
+		}},
+		{domain.RetentionRevisions, policy.Revisions, func(cutoff time.Time) (int64, error) {
+			if report.DryRun {
+				return r.users.CountRevisions(ctx, scope, cutoff)
+			}
+			return r.users.PurgeRevisions(ctx, scope, cutoff)
+		}},
+	}
+	for _, rule := range rules {
+		if rule.retention <= 0 {
+			continue
+		}
+		cutoff := time.Now().UTC().Add(-rule.retention)
+		affected, err := rule.apply(cutoff)
+		report.Results = append(report.Results, &domain.RetentionResult{AddressBook: policy.AddressBook, Rule: rule.name, Cutoff: cutoff, Affected: affected})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trashStale moves the users in the scope that have not changed since the
+// cutoff to the trash and returns how many there were
+func (r *retentionUseCase) trashStale(ctx context.Context, scope domain.RetentionScope, cutoff time.Time, dryRun bool) (int64, error) {
+	stale, err := r.users.FindStale(ctx, scope, cutoff)
+	if err != nil || dryRun {
+		return int64(len(stale)), err
+	}
+
+	var trashed int64
+	for _, id := range stale {
+		// A user changed or deleted in the meantime is skipped
+		_, err := r.users.DeleteUser(ctx, bson.M{"_id": id, "$or": bson.A{
+			bson.M{"updatedAt": bson.M{"$lt": cutoff}},
+			bson.M{"updatedAt": bson.M{"$exists": false}},
+		}}, domain.ActorRetention)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		}
+		if err != nil {
+			return trashed, err
+		}
+		trashed++
+	}
+	return trashed, nil
+}
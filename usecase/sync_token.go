@@ -0,0 +1,38 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidSyncToken is returned for a sync token that was not issued by this service
+	ErrInvalidSyncToken = errors.New("invalid sync token")
+
+	// ErrSyncTokenExpired is returned when changes since the token are no longer
+	// known and the client has to sync from scratch
+	ErrSyncTokenExpired = errors.New("sync token expired")
+)
+
+const syncTokenPrefix = "v1:"
+
+// encodeSyncToken wraps a change sequence number in an opaque sync token
+func encodeSyncToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(syncTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+// decodeSyncToken returns the change sequence number of a sync token
+func decodeSyncToken(token string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(raw), syncTokenPrefix) {
+		return 0, ErrInvalidSyncToken
+	}
+
+	seq, err := strconv.ParseInt(strings.TrimPrefix(string(raw), syncTokenPrefix), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidSyncToken
+	}
+	return seq, nil
+}
@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+	"testing"
+	"time"
+)
+
+func TestSyncTokenRoundTrip(t *testing.T) {
+	for _, seq := range []int64{0, 1, 42, 1 << 40} {
+		got, err := decodeSyncToken(encodeSyncToken(seq))
+		if err != nil || got != seq {
+			t.Errorf("decodeSyncToken(encodeSyncToken(%d)) = %d, %v", seq, got, err)
+		}
+	}
+}
+
+func TestDecodeSyncTokenInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "not a token!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("v1:1"))},
+		{"missing prefix", encode("42")},
+		{"other version", encode("v2:42")},
+		{"no sequence", encode("v1:")},
+		{"not a number", encode("v1:abc")},
+		{"negative sequence", encode("v1:-1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSyncToken(tt.token); !errors.Is(err, ErrInvalidSyncToken) {
+				t.Errorf("decodeSyncToken(%q) error = %v, want %v", tt.token, err, ErrInvalidSyncToken)
+			}
+		})
+	}
+}
+
+// syncUsersRepo serves the change log of a sync from memory
+type syncUsersRepo struct {
+	repository.UsersRepo
+	horizon int64
+	changes []*domain.User
+}
+
+func (r *syncUsersRepo) SyncHorizon(ctx context.Context) (int64, error) {
+	return r.horizon, nil
+}
+
+func (r *syncUsersRepo) FindChanges(ctx context.Context, since int64, limit int64) ([]*domain.User, error) {
+	changes := make([]*domain.User, 0)
+	for _, user := range r.changes {
+		if user.ChangeSeq > since && int64(len(changes)) < limit {
+			changes = append(changes, user)
+		}
+	}
+	return changes, nil
+}
+
+func TestSyncUsersHorizon(t *testing.T) {
+	deletedAt := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	repo := &syncUsersRepo{
+		horizon: 10,
+		changes: []*domain.User{
+			{Username: "ada", ChangeSeq: 11},
+			{Username: "grace", ChangeSeq: 12, DeletedAt: &deletedAt},
+			{Username: "alan", ChangeSeq: 13},
+		},
+	}
+	u := &usersUseCase{repo: repo}
+
+	tests := []struct {
+		name        string
+		since       int64
+		limit       int64
+		wantErr     error
+		wantUsers   int
+		wantDeleted int
+		wantNext    int64
+		wantMore    bool
+	}{
+		{name: "before the horizon", since: 9, limit: 10, wantErr: ErrSyncTokenExpired},
+		{name: "long before the horizon", since: 0, limit: 10, wantErr: ErrSyncTokenExpired},
+		{name: "at the horizon", since: 10, limit: 10, wantUsers: 2, wantDeleted: 1, wantNext: 13},
+		{name: "after the horizon", since: 12, limit: 10, wantUsers: 1, wantNext: 13},
+		{name: "up to date", since: 13, limit: 10, wantNext: 13},
+		{name: "more than the limit", since: 10, limit: 2, wantUsers: 1, wantDeleted: 1, wantNext: 12, wantMore: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := u.SyncUsers(context.Background(), encodeSyncToken(tt.since), tt.limit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SyncUsers() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			next, _ := decodeSyncToken(result.NextToken)
+			if len(result.Users) != tt.wantUsers || len(result.Deleted) != tt.wantDeleted || next != tt.wantNext || result.HasMore != tt.wantMore || result.Full {
+				t.Errorf("SyncUsers() = %d users, %d deleted, next %d, more %v, full %v, want %d, %d, %d, %v, false",
+					len(result.Users), len(result.Deleted), next, result.HasMore, result.Full,
+					tt.wantUsers, tt.wantDeleted, tt.wantNext, tt.wantMore)
+			}
+		})
+	}
+
+	if _, err := u.SyncUsers(context.Background(), "garbage", 10); !errors.Is(err, ErrInvalidSyncToken) {
+		t.Errorf("SyncUsers() with an invalid token error = %v, want %v", err, ErrInvalidSyncToken)
+	}
+}
@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTagLength is the longest tag, in characters, a user can carry
+const maxTagLength = 64
+
+// ErrInvalidTag is returned for a tag that is empty or too long
+var ErrInvalidTag = errors.New("invalid tag")
+
+// normalizeTags trims and lower-cases the tags and drops duplicates, so
+// "VIP" and " vip" end up as the same tag
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
@@ -1,76 +1,550 @@
 package usecase
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"findApi/domain"
 	"findApi/repository"
+	"slices"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // UsersUseCase defines the interface for use case operations for managing users.
 type UsersUseCase interface {
 	// CreateUser adds a new user using either the username or phone number
-	CreateUser(user *domain.User) (*domain.User, error)
+	CreateUser(ctx context.Context, user *domain.User, actor string) (*domain.User, error)
 
 	// GetUserByUsername retrieves a user by their username
-	GetUserByUsername(username string) (*domain.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*domain.User, error)
 
 	// GetUserByPhone retrieves a user by their phone number
-	GetUserByPhone(phone string) (*domain.User, error)
+	GetUserByPhone(ctx context.Context, phone string) (*domain.User, error)
+
+	// LookupUsers retrieves the users with any of the usernames or phone numbers of the request
+	LookupUsers(ctx context.Context, req domain.LookupReq) (*domain.LookupResult, error)
+
+	// DiscoverContacts tells which of the hashed phone numbers of the request are in the book
+	DiscoverContacts(ctx context.Context, req domain.DiscoveryReq) (*domain.DiscoveryResult, error)
 
 	// UpdateUser updates a user by username or phone
-	UpdateUser(filter bson.M, user *domain.User) error
+	UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) error
+
+	// DeleteUser moves a user to the trash by username or phone
+	DeleteUser(ctx context.Context, filter bson.M, actor string) error
+
+	// FindTrash retrieves all users in the trash
+	FindTrash(ctx context.Context) ([]*domain.User, error)
+
+	// RestoreUser moves a user out of the trash
+	RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) error
+
+	// PurgeTrash permanently removes users in the scope that have been in the trash longer than retention
+	PurgeTrash(ctx context.Context, scope domain.RetentionScope, retention time.Duration) (int64, error)
+
+	// FindAllUsers retrieves all users matching the filter
+	FindAllUsers(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error)
+
+	// FacetUsers counts the users matching the filter per tag and how many are favorites
+	FacetUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserFacets, error)
+
+	// TagUsers adds and removes tags and sets the favorite flag on several users at once
+	TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) error
+
+	// SetPhoto stores the photo of a user together with its thumbnails
+	SetPhoto(ctx context.Context, id primitive.ObjectID, data []byte, actor string) (*domain.Photo, error)
+
+	// GetPhoto retrieves the photo of a user in a thumbnail size, or the original when size is 0
+	GetPhoto(ctx context.Context, id primitive.ObjectID, size int) ([]byte, string, error)
+
+	// DeletePhoto removes the photo of a user
+	DeletePhoto(ctx context.Context, id primitive.ObjectID, actor string) error
+
+	// ExportVCard renders a user as a vCard carrying its photo
+	ExportVCard(ctx context.Context, id primitive.ObjectID) ([]byte, error)
+
+	// UpcomingReminders lists the birthdays and anniversaries within days of the day of now, in its time zone
+	UpcomingReminders(ctx context.Context, now time.Time, days int) ([]*domain.Reminder, error)
+
+	// FindRevisions retrieves the prior versions of a user
+	FindRevisions(ctx context.Context, id primitive.ObjectID) ([]*domain.Revision, error)
+
+	// RevertUser restores a user to a prior version
+	RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) error
 
-	// DeleteUser deletes a user by username or phone
-	DeleteUser(filter bson.M) error
+	// SyncUsers retrieves up to limit user changes since the sync token, or all users without one
+	SyncUsers(ctx context.Context, token string, limit int64) (*domain.SyncResult, error)
 
-	// FindAllUsers retrieves all users
-	FindAllUsers() ([]*domain.User, error)
+	// FindDuplicates retrieves pairs of users that likely describe the same person
+	FindDuplicates(ctx context.Context, minScore float64) ([]*domain.DuplicateCandidate, error)
+
+	// MergeUsers folds the duplicates into the primary user, resolving conflicts with the rules
+	MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, usernameRule, phoneRule, actor string) (*domain.User, error)
 }
 
+// ErrInvalidHash is returned for phone hashes or hash prefixes that are not hex-encoded or have the wrong length
+var ErrInvalidHash = errors.New("invalid hash")
+
 type usersUseCase struct {
-	repo repository.UsersRepo
+	repo          repository.UsersRepo
+	photos        repository.BlobStore
+	relationships repository.RelationshipsRepo
+	interactions  repository.InteractionsRepo
 }
 
-// NewUsersUseCase creates a new instance of UsersUseCase with the given repository
-func NewUsersUseCase(repo repository.UsersRepo) UsersUseCase {
-	return &usersUseCase{
-		repo: repo,
-	}
+// NewUsersUseCase creates a new instance of UsersUseCase with the given repositories and photo store.
+// Every operation is traced in a span.
+func NewUsersUseCase(repo repository.UsersRepo, photos repository.BlobStore, relationships repository.RelationshipsRepo, interactions repository.InteractionsRepo) UsersUseCase {
+	return &tracedUsersUseCase{next: &usersUseCase{
+		repo:          repo,
+		photos:        photos,
+		relationships: relationships,
+		interactions:  interactions,
+	}}
 }
 
 // CreateUser adds a new user using either the username or phone number
-func (u *usersUseCase) CreateUser(user *domain.User) (*domain.User, error) {
+func (u *usersUseCase) CreateUser(ctx context.Context, user *domain.User, actor string) (*domain.User, error) {
 	// Validation or additional business logic can be added here
-	return u.repo.InsertUser(user)
+	tags, err := normalizeTags(user.Tags)
+	if err != nil {
+		return nil, err
+	}
+	user.Tags = tags
+	if err := validateDates(user); err != nil {
+		return nil, err
+	}
+	// Photos are only ever set by SetPhoto, which generates their ids
+	user.Photo = nil
+	return u.repo.InsertUser(ctx, user, actor)
 }
 
 // GetUserByUsername retrieves a user by their username
-func (u *usersUseCase) GetUserByUsername(username string) (*domain.User, error) {
+func (u *usersUseCase) GetUserByUsername(ctx context.Context, username string) (*domain.User, error) {
 	// Get user by username
-	return u.repo.GetByUsername(username)
+	return u.repo.GetByUsername(ctx, username)
 }
 
 // GetUserByPhone retrieves a user by their phone number
-func (u *usersUseCase) GetUserByPhone(phone string) (*domain.User, error) {
+func (u *usersUseCase) GetUserByPhone(ctx context.Context, phone string) (*domain.User, error) {
 	// Get user by phone number
-	return u.repo.GetByPhone(phone)
+	return u.repo.GetByPhone(ctx, phone)
+}
+
+// LookupUsers retrieves the users with any of the usernames or phone numbers
+// of the request, listing the identifiers that matched none
+func (u *usersUseCase) LookupUsers(ctx context.Context, req domain.LookupReq) (*domain.LookupResult, error) {
+	// Look every identifier up once, leaving out empty ones
+	usernames, phones := uniqueIdentifiers(req.Usernames), uniqueIdentifiers(req.Phones)
+	users, err := u.repo.FindByIdentifiers(ctx, usernames, phones)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, 2*len(users))
+	for _, user := range users {
+		found["username:"+user.Username] = true
+		found["phone:"+user.Phone] = true
+	}
+	result := &domain.LookupResult{Users: users, NotFound: domain.LookupReq{Usernames: []string{}, Phones: []string{}}}
+	for _, username := range usernames {
+		if !found["username:"+username] {
+			result.NotFound.Usernames = append(result.NotFound.Usernames, username)
+		}
+	}
+	for _, phone := range phones {
+		if !found["phone:"+phone] {
+			result.NotFound.Phones = append(result.NotFound.Phones, phone)
+		}
+	}
+	return result, nil
+}
+
+// DiscoverContacts tells which of the hashed phone numbers of the request are
+// in the book, matching whole hashes and listing the hashes in the buckets of
+// the prefixes
+func (u *usersUseCase) DiscoverContacts(ctx context.Context, req domain.DiscoveryReq) (*domain.DiscoveryResult, error) {
+	hashes, err := normalizeHashes(req.Hashes, sha256.Size*2)
+	if err != nil {
+		return nil, err
+	}
+	prefixes, err := normalizeHashes(req.Prefixes, domain.DiscoveryBucketLength)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.DiscoveryResult{Matches: []string{}, Buckets: map[string][]string{}}
+	if len(hashes) > 0 {
+		if result.Matches, err = u.repo.MatchPhoneHashes(ctx, hashes); err != nil {
+			return nil, err
+		}
+	}
+	if len(prefixes) > 0 {
+		if result.Buckets, err = u.repo.FindPhoneBuckets(ctx, prefixes); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// normalizeHashes lowercases hex-encoded hashes or hash prefixes, dropping
+// repeated ones. It fails with ErrInvalidHash unless every one has length hex digits.
+func normalizeHashes(hashes []string, length int) ([]string, error) {
+	normalized := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		hash = strings.ToLower(hash)
+		// Prefixes may have an odd number of digits, so check them one by one
+		// rather than decoding them
+		if len(hash) != length || strings.Trim(hash, "0123456789abcdef") != "" {
+			return nil, ErrInvalidHash
+		}
+		normalized = append(normalized, hash)
+	}
+	return uniqueIdentifiers(normalized), nil
+}
+
+// uniqueIdentifiers returns the identifiers without empty and repeated ones, in their order
+func uniqueIdentifiers(identifiers []string) []string {
+	unique := make([]string, 0, len(identifiers))
+	seen := make(map[string]bool, len(identifiers))
+	for _, identifier := range identifiers {
+		if identifier == "" || seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		unique = append(unique, identifier)
+	}
+	return unique
 }
 
 // UpdateUser updates a user by username or phone
-func (u *usersUseCase) UpdateUser(filter bson.M, user *domain.User) error {
+func (u *usersUseCase) UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) error {
 	// Business logic for updating the user can be added here (e.g., validating fields)
-	return u.repo.UpdateUser(filter, user)
+	for _, date := range []*domain.Date{user.Birthday, user.Anniversary} {
+		// A date without a month clears it
+		if date != nil && date.Month != 0 {
+			if err := validateDate(date, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := u.repo.UpdateUser(ctx, filter, user, actor)
+	return err
 }
 
-// DeleteUser deletes a user by username or phone
-func (u *usersUseCase) DeleteUser(filter bson.M) error {
+// DeleteUser moves a user to the trash by username or phone
+func (u *usersUseCase) DeleteUser(ctx context.Context, filter bson.M, actor string) error {
 	// Business logic for deleting a user can be added here
-	return u.repo.DeleteUser(filter)
+	_, err := u.repo.DeleteUser(ctx, filter, actor)
+	return err
 }
 
-// FindAllUsers retrieves all users
-func (u *usersUseCase) FindAllUsers() ([]*domain.User, error) {
+// FindAllUsers retrieves all users matching the filter
+func (u *usersUseCase) FindAllUsers(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
 	// Get all users from the repository
-	return u.repo.FindAll()
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+	return u.repo.FindAll(ctx, filter)
+}
+
+// FacetUsers counts the users matching the filter per tag and how many are favorites
+func (u *usersUseCase) FacetUsers(ctx context.Context, filter domain.UserFilter) (*domain.UserFacets, error) {
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+	return u.repo.FacetUsers(ctx, filter)
+}
+
+// TagUsers adds and removes tags and sets the favorite flag on several users at once
+func (u *usersUseCase) TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) error {
+	add, err := normalizeTags(add)
+	if err != nil {
+		return err
+	}
+	remove, err = normalizeTags(remove)
+	if err != nil {
+		return err
+	}
+	return u.repo.TagUsers(ctx, userIDs, add, remove, favorite, actor)
+}
+
+// FindTrash retrieves all users in the trash
+func (u *usersUseCase) FindTrash(ctx context.Context) ([]*domain.User, error) {
+	return u.repo.FindTrash(ctx)
+}
+
+// RestoreUser moves a user out of the trash
+func (u *usersUseCase) RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) error {
+	_, err := u.repo.RestoreUser(ctx, id, actor)
+	return err
+}
+
+// PurgeTrash permanently removes users in the scope that have been in the trash longer than retention
+func (u *usersUseCase) PurgeTrash(ctx context.Context, scope domain.RetentionScope, retention time.Duration) (int64, error) {
+	purged, err := u.repo.PurgeTrash(ctx, scope, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return int64(len(purged)), err
+	}
+
+	// Drop the relationships, interactions and photos of purged users, which are no longer reachable
+	if err := u.relationships.DeleteForUsers(ctx, purged); err != nil {
+		return int64(len(purged)), err
+	}
+	if err := u.interactions.DeleteForUsers(ctx, purged); err != nil {
+		return int64(len(purged)), err
+	}
+	for _, id := range purged {
+		if err := u.photos.Delete(ctx, id.Hex()); err != nil {
+			return int64(len(purged)), err
+		}
+	}
+	return int64(len(purged)), nil
+}
+
+// FindRevisions retrieves the prior versions of a user
+func (u *usersUseCase) FindRevisions(ctx context.Context, id primitive.ObjectID) ([]*domain.Revision, error) {
+	return u.repo.FindRevisions(ctx, id)
+}
+
+// RevertUser restores a user to a prior version
+func (u *usersUseCase) RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) error {
+	_, err := u.repo.RevertUser(ctx, id, rev, actor)
+	return err
+}
+
+// SyncUsers retrieves up to limit user changes since the sync token, or all users without one
+func (u *usersUseCase) SyncUsers(ctx context.Context, token string, limit int64) (*domain.SyncResult, error) {
+	if token == "" {
+		// Read the sequence first, so changes racing with the snapshot are sent again on the next sync
+		seq, err := u.repo.CurrentSequence(ctx)
+		if err != nil {
+			return nil, err
+		}
+		users, err := u.repo.FindAll(ctx, domain.UserFilter{})
+		if err != nil {
+			return nil, err
+		}
+		return &domain.SyncResult{
+			Full:      true,
+			Users:     users,
+			Deleted:   make([]*domain.Tombstone, 0),
+			NextToken: encodeSyncToken(seq),
+		}, nil
+	}
+
+	since, err := decodeSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+	horizon, err := u.repo.SyncHorizon(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if since < horizon {
+		return nil, ErrSyncTokenExpired
+	}
+
+	// Read one extra change to learn whether there are more
+	changes, err := u.repo.FindChanges(ctx, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.SyncResult{
+		Users:   make([]*domain.User, 0),
+		Deleted: make([]*domain.Tombstone, 0),
+	}
+	if int64(len(changes)) > limit {
+		changes = changes[:limit]
+		result.HasMore = true
+	}
+
+	for _, user := range changes {
+		if user.DeletedAt != nil {
+			result.Deleted = append(result.Deleted, &domain.Tombstone{ID: user.ID, DeletedAt: *user.DeletedAt})
+		} else {
+			result.Users = append(result.Users, user)
+		}
+		since = user.ChangeSeq
+	}
+	result.NextToken = encodeSyncToken(since)
+	return result, nil
+}
+
+// FindDuplicates retrieves pairs of users that likely describe the same person
+func (u *usersUseCase) FindDuplicates(ctx context.Context, minScore float64) ([]*domain.DuplicateCandidate, error) {
+	users, err := u.repo.FindAll(ctx, domain.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return findDuplicates(users, minScore), nil
+}
+
+// MergeUsers folds the duplicates into the primary user, resolving conflicts with the rules
+func (u *usersUseCase) MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, usernameRule, phoneRule, actor string) (*domain.User, error) {
+	primary, err := u.getActiveUser(ctx, primaryID)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates := make([]*domain.User, 0, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		duplicate, err := u.getActiveUser(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, duplicate)
+	}
+
+	merged := resolveMerge(primary, duplicates, usernameRule, phoneRule)
+
+	// The primary takes over the relationships and interactions of the duplicates,
+	// within the transaction of the merge
+	return u.repo.MergeUsers(ctx, primaryID, duplicateIDs, merged, actor, func(ctx context.Context) error {
+		if err := u.relationships.ReassignUsers(ctx, duplicateIDs, primaryID); err != nil {
+			return err
+		}
+		return u.interactions.ReassignUsers(ctx, duplicateIDs, primaryID)
+	})
+}
+
+// SetPhoto stores the photo of a user together with its thumbnails
+func (u *usersUseCase) SetPhoto(ctx context.Context, id primitive.ObjectID, data []byte, actor string) (*domain.Photo, error) {
+	user, err := u.getActiveUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	photo, thumbnails, err := processPhoto(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := photoBlobDir(id, photo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the blobs before pointing the user at them. Cleaning up goes on
+	// even when the caller has gone away.
+	cleanup := context.WithoutCancel(ctx)
+	if err := u.photos.Put(ctx, photoBlobIn(dir, 0), data); err != nil {
+		return nil, err
+	}
+	for size, thumbnail := range thumbnails {
+		if err := u.photos.Put(ctx, photoBlobIn(dir, size), thumbnail); err != nil {
+			u.photos.Delete(cleanup, dir)
+			return nil, err
+		}
+	}
+	if err := u.repo.SetPhoto(ctx, id, photo, actor); err != nil {
+		u.photos.Delete(cleanup, dir)
+		return nil, err
+	}
+
+	// Drop the blobs of the replaced photo
+	if user.Photo != nil {
+		if replaced, err := photoBlobDir(id, user.Photo.ID); err == nil {
+			u.photos.Delete(cleanup, replaced)
+		}
+	}
+	return photo, nil
+}
+
+// GetPhoto retrieves the photo of a user in a thumbnail size, or the original when size is 0
+func (u *usersUseCase) GetPhoto(ctx context.Context, id primitive.ObjectID, size int) ([]byte, string, error) {
+	if size != 0 && !slices.Contains(domain.PhotoSizes, size) {
+		return nil, "", ErrInvalidPhotoSize
+	}
+	user, err := u.getActiveUser(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Photo == nil {
+		return nil, "", repository.ErrBlobNotFound
+	}
+
+	name, err := photoBlobName(id, user.Photo.ID, size)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := u.photos.Get(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	if size == 0 {
+		return data, user.Photo.ContentType, nil
+	}
+	return data, "image/jpeg", nil
+}
+
+// DeletePhoto removes the photo of a user
+func (u *usersUseCase) DeletePhoto(ctx context.Context, id primitive.ObjectID, actor string) error {
+	user, err := u.getActiveUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Photo == nil {
+		return repository.ErrBlobNotFound
+	}
+	if err := u.repo.SetPhoto(ctx, id, nil, actor); err != nil {
+		return err
+	}
+	dir, err := photoBlobDir(id, user.Photo.ID)
+	if err != nil {
+		// No blobs are stored under a photo id that was not generated
+		return nil
+	}
+	// The photo is gone from the user, so its blobs go even when the caller has gone away
+	return u.photos.Delete(context.WithoutCancel(ctx), dir)
+}
+
+// ExportVCard renders a user as a vCard carrying its largest thumbnail as photo
+func (u *usersUseCase) ExportVCard(ctx context.Context, id primitive.ObjectID) ([]byte, error) {
+	user, err := u.getActiveUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var photo []byte
+	if user.Photo != nil {
+		size := domain.PhotoSizes[len(domain.PhotoSizes)-1]
+		name, err := photoBlobName(id, user.Photo.ID, size)
+		if err != nil {
+			return nil, err
+		}
+		if photo, err = u.photos.Get(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return encodeVCard(user, photo), nil
+}
+
+// UpcomingReminders lists the birthdays and anniversaries within days of the day of now, in its time zone
+func (u *usersUseCase) UpcomingReminders(ctx context.Context, now time.Time, days int) ([]*domain.Reminder, error) {
+	users, err := u.repo.FindAll(ctx, domain.UserFilter{WithDates: true})
+	if err != nil {
+		return nil, err
+	}
+	return upcomingReminders(users, now, days), nil
+}
+
+// getActiveUser retrieves a user by id that is not in the trash
+func (u *usersUseCase) getActiveUser(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+	user, err := u.repo.GetUser(ctx, bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || user.DeletedAt != nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	return user, nil
 }
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"findApi/domain"
+	"findApi/internal/tracing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// tracedUsersUseCase traces every operation of the users use case in a span
+type tracedUsersUseCase struct {
+	next UsersUseCase
+}
+
+// start starts tracing an operation and returns the context to run it with
+// and the function to call with its outcome
+func (u *tracedUsersUseCase) start(ctx context.Context, operation string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "usersUseCase."+operation)
+	return ctx, func(err error) {
+		tracing.End(span, err)
+	}
+}
+
+func (u *tracedUsersUseCase) CreateUser(ctx context.Context, user *domain.User, actor string) (result *domain.User, err error) {
+	ctx, end := u.start(ctx, "CreateUser")
+	defer func() { end(err) }()
+	return u.next.CreateUser(ctx, user, actor)
+}
+
+func (u *tracedUsersUseCase) GetUserByUsername(ctx context.Context, username string) (result *domain.User, err error) {
+	ctx, end := u.start(ctx, "GetUserByUsername")
+	defer func() { end(err) }()
+	return u.next.GetUserByUsername(ctx, username)
+}
+
+func (u *tracedUsersUseCase) GetUserByPhone(ctx context.Context, phone string) (result *domain.User, err error) {
+	ctx, end := u.start(ctx, "GetUserByPhone")
+	defer func() { end(err) }()
+	return u.next.GetUserByPhone(ctx, phone)
+}
+
+func (u *tracedUsersUseCase) LookupUsers(ctx context.Context, req domain.LookupReq) (result *domain.LookupResult, err error) {
+	ctx, end := u.start(ctx, "LookupUsers")
+	defer func() { end(err) }()
+	return u.next.LookupUsers(ctx, req)
+}
+
+func (u *tracedUsersUseCase) DiscoverContacts(ctx context.Context, req domain.DiscoveryReq) (result *domain.DiscoveryResult, err error) {
+	ctx, end := u.start(ctx, "DiscoverContacts")
+	defer func() { end(err) }()
+	return u.next.DiscoverContacts(ctx, req)
+}
+
+func (u *tracedUsersUseCase) UpdateUser(ctx context.Context, filter bson.M, user *domain.User, actor string) (err error) {
+	ctx, end := u.start(ctx, "UpdateUser")
+	defer func() { end(err) }()
+	return u.next.UpdateUser(ctx, filter, user, actor)
+}
+
+func (u *tracedUsersUseCase) DeleteUser(ctx context.Context, filter bson.M, actor string) (err error) {
+	ctx, end := u.start(ctx, "DeleteUser")
+	defer func() { end(err) }()
+	return u.next.DeleteUser(ctx, filter, actor)
+}
+
+func (u *tracedUsersUseCase) FindTrash(ctx context.Context) (result []*domain.User, err error) {
+	ctx, end := u.start(ctx, "FindTrash")
+	defer func() { end(err) }()
+	return u.next.FindTrash(ctx)
+}
+
+func (u *tracedUsersUseCase) RestoreUser(ctx context.Context, id primitive.ObjectID, actor string) (err error) {
+	ctx, end := u.start(ctx, "RestoreUser")
+	defer func() { end(err) }()
+	return u.next.RestoreUser(ctx, id, actor)
+}
+
+func (u *tracedUsersUseCase) PurgeTrash(ctx context.Context, scope domain.RetentionScope, retention time.Duration) (result int64, err error) {
+	ctx, end := u.start(ctx, "PurgeTrash")
+	defer func() { end(err) }()
+	return u.next.PurgeTrash(ctx, scope, retention)
+}
+
+func (u *tracedUsersUseCase) FindAllUsers(ctx context.Context, filter domain.UserFilter) (result []*domain.User, err error) {
+	ctx, end := u.start(ctx, "FindAllUsers")
+	defer func() { end(err) }()
+	return u.next.FindAllUsers(ctx, filter)
+}
+
+func (u *tracedUsersUseCase) FacetUsers(ctx context.Context, filter domain.UserFilter) (result *domain.UserFacets, err error) {
+	ctx, end := u.start(ctx, "FacetUsers")
+	defer func() { end(err) }()
+	return u.next.FacetUsers(ctx, filter)
+}
+
+func (u *tracedUsersUseCase) TagUsers(ctx context.Context, userIDs []primitive.ObjectID, add, remove []string, favorite *bool, actor string) (err error) {
+	ctx, end := u.start(ctx, "TagUsers")
+	defer func() { end(err) }()
+	return u.next.TagUsers(ctx, userIDs, add, remove, favorite, actor)
+}
+
+func (u *tracedUsersUseCase) SetPhoto(ctx context.Context, id primitive.ObjectID, data []byte, actor string) (result *domain.Photo, err error) {
+	ctx, end := u.start(ctx, "SetPhoto")
+	defer func() { end(err) }()
+	return u.next.SetPhoto(ctx, id, data, actor)
+}
+
+func (u *tracedUsersUseCase) GetPhoto(ctx context.Context, id primitive.ObjectID, size int) (result []byte, contentType string, err error) {
+	ctx, end := u.start(ctx, "GetPhoto")
+	defer func() { end(err) }()
+	return u.next.GetPhoto(ctx, id, size)
+}
+
+func (u *tracedUsersUseCase) DeletePhoto(ctx context.Context, id primitive.ObjectID, actor string) (err error) {
+	ctx, end := u.start(ctx, "DeletePhoto")
+	defer func() { end(err) }()
+	return u.next.DeletePhoto(ctx, id, actor)
+}
+
+func (u *tracedUsersUseCase) ExportVCard(ctx context.Context, id primitive.ObjectID) (result []byte, err error) {
+	ctx, end := u.start(ctx, "ExportVCard")
+	defer func() { end(err) }()
+	return u.next.ExportVCard(ctx, id)
+}
+
+func (u *tracedUsersUseCase) UpcomingReminders(ctx context.Context, now time.Time, days int) (result []*domain.Reminder, err error) {
+	ctx, end := u.start(ctx, "UpcomingReminders")
+	defer func() { end(err) }()
+	return u.next.UpcomingReminders(ctx, now, days)
+}
+
+func (u *tracedUsersUseCase) FindRevisions(ctx context.Context, id primitive.ObjectID) (result []*domain.Revision, err error) {
+	ctx, end := u.start(ctx, "FindRevisions")
+	defer func() { end(err) }()
+	return u.next.FindRevisions(ctx, id)
+}
+
+func (u *tracedUsersUseCase) RevertUser(ctx context.Context, id primitive.ObjectID, rev int, actor string) (err error) {
+	ctx, end := u.start(ctx, "RevertUser")
+	defer func() { end(err) }()
+	return u.next.RevertUser(ctx, id, rev, actor)
+}
+
+func (u *tracedUsersUseCase) SyncUsers(ctx context.Context, token string, limit int64) (result *domain.SyncResult, err error) {
+	ctx, end := u.start(ctx, "SyncUsers")
+	defer func() { end(err) }()
+	return u.next.SyncUsers(ctx, token, limit)
+}
+
+func (u *tracedUsersUseCase) FindDuplicates(ctx context.Context, minScore float64) (result []*domain.DuplicateCandidate, err error) {
+	ctx, end := u.start(ctx, "FindDuplicates")
+	defer func() { end(err) }()
+	return u.next.FindDuplicates(ctx, minScore)
+}
+
+func (u *tracedUsersUseCase) MergeUsers(ctx context.Context, primaryID primitive.ObjectID, duplicateIDs []primitive.ObjectID, usernameRule, phoneRule, actor string) (result *domain.User, err error) {
+	ctx, end := u.start(ctx, "MergeUsers")
+	defer func() { end(err) }()
+	return u.next.MergeUsers(ctx, primaryID, duplicateIDs, usernameRule, phoneRule, actor)
+}
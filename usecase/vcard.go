@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"findApi/domain"
+	"strings"
+)
+
+// vcardLineLength is the longest line, in octets, before a vCard line is folded
+const vcardLineLength = 75
+
+// vcardEscaper escapes the characters with a meaning in vCard text values.
+// Every line break, CRLF, LF or a bare CR, becomes an escaped newline, so a
+// value can never start a property of its own.
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// encodeVCard renders a user as a vCard 3.0, with the JPEG photo inline when given
+func encodeVCard(user *domain.User, photo []byte) []byte {
+	var b strings.Builder
+	writeLine := func(line string) {
+		// Fold long lines, continuing them on lines starting with a space
+		for len(line) > vcardLineLength {
+			cut := vcardLineLength
+			for cut > 0 && !isRuneStart(line[cut]) {
+				cut--
+			}
+			b.WriteString(line[:cut] + "\r\n")
+			line = " " + line[cut:]
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	writeLine("BEGIN:VCARD")
+	writeLine("VERSION:3.0")
+	writeLine("UID:" + user.ID.Hex())
+	writeLine("FN:" + vcardEscaper.Replace(user.Username))
+	writeLine("N:" + vcardEscaper.Replace(user.Username) + ";;;;")
+	if user.Phone != "" {
+		writeLine("TEL;TYPE=CELL:" + vcardEscaper.Replace(user.Phone))
+	}
+	if len(user.Tags) > 0 {
+		categories := make([]string, 0, len(user.Tags))
+		for _, tag := range user.Tags {
+			categories = append(categories, vcardEscaper.Replace(tag))
+		}
+		writeLine("CATEGORIES:" + strings.Join(categories, ","))
+	}
+	if photo != nil {
+		writeLine("PHOTO;ENCODING=b;TYPE=JPEG:" + base64.StdEncoding.EncodeToString(photo))
+	}
+	writeLine("END:VCARD")
+	return []byte(b.String())
+}
+
+// isRuneStart reports whether the byte starts a UTF-8 encoded character
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
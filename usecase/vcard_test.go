@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"findApi/domain"
+	"strings"
+	"testing"
+)
+
+func TestVCardEscaper(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Ada Lovelace", "Ada Lovelace"},
+		{`back\slash`, `back\\slash`},
+		{"a,b;c", `a\,b\;c`},
+		{"line\nbreak", `line\nbreak`},
+		{"line\r\nbreak", `line\nbreak`},
+		{"bare\rreturn", `bare\nreturn`},
+		{"\r\r\n\n", `\n\n\n`},
+	}
+	for _, tt := range tests {
+		if got := vcardEscaper.Replace(tt.value); got != tt.want {
+			t.Errorf("vcardEscaper.Replace(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeVCardLineBreaks(t *testing.T) {
+	user := &domain.User{
+		Username: "Ada\rTEL:+15550000000\r\nEMAIL:ada@example.com\nNOTE:x",
+		Phone:    "+15551234567\rX-INJECTED:1",
+		Tags:     []string{"family\rwork"},
+	}
+	card := string(encodeVCard(user, nil))
+
+	// Lines end in CRLF, and no line break survives within a line
+	lines := strings.Split(strings.TrimSuffix(card, "\r\n"), "\r\n")
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\r\n") {
+			t.Errorf("line %q holds a line break", line)
+		}
+	}
+	properties := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !strings.HasPrefix(line, " ") {
+			properties = append(properties, strings.SplitN(line, ":", 2)[0])
+		}
+	}
+	want := []string{"BEGIN", "VERSION", "UID", "FN", "N", "TEL;TYPE=CELL", "CATEGORIES", "END"}
+	if strings.Join(properties, " ") != strings.Join(want, " ") {
+		t.Errorf("encodeVCard() properties = %q, want %q", properties, want)
+	}
+}
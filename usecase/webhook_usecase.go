@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"findApi/domain"
+	"findApi/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebhookDeliverer delivers a single event to a single webhook
+type WebhookDeliverer interface {
+	// Deliver queues the event for delivery without waiting for it to be delivered
+	Deliver(ctx context.Context, webhook *domain.Webhook, event *domain.Event) error
+}
+
+// WebhooksUseCase defines the interface for use case operations for managing webhooks.
+type WebhooksUseCase interface {
+	// CreateWebhook adds a new webhook subscription
+	CreateWebhook(ctx context.Context, webhook *domain.Webhook) (*domain.Webhook, error)
+
+	// FindWebhooks retrieves all webhooks without their secrets
+	FindWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+
+	// DeleteWebhook removes a webhook subscription
+	DeleteWebhook(ctx context.Context, id primitive.ObjectID) error
+
+	// FindDeliveries retrieves the delivery log of a webhook
+	FindDeliveries(ctx context.Context, webhookID primitive.ObjectID) ([]*domain.Delivery, error)
+
+	// FindDeadLetters retrieves all events that could not be delivered
+	FindDeadLetters(ctx context.Context) ([]*domain.DeadLetter, error)
+
+	// RetryDeadLetter takes an event off the dead-letter queue and delivers it again
+	RetryDeadLetter(ctx context.Context, id primitive.ObjectID) error
+}
+
+type webhooksUseCase struct {
+	repo      repository.WebhooksRepo
+	deliverer WebhookDeliverer
+}
+
+// NewWebhooksUseCase creates a new instance of WebhooksUseCase with the given repository and deliverer
+func NewWebhooksUseCase(repo repository.WebhooksRepo, deliverer WebhookDeliverer) WebhooksUseCase {
+	return &webhooksUseCase{
+		repo:      repo,
+		deliverer: deliverer,
+	}
+}
+
+// CreateWebhook adds a new webhook subscription
+func (w *webhooksUseCase) CreateWebhook(ctx context.Context, webhook *domain.Webhook) (*domain.Webhook, error) {
+	return w.repo.InsertWebhook(ctx, webhook)
+}
+
+// FindWebhooks retrieves all webhooks without their secrets
+func (w *webhooksUseCase) FindWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	webhooks, err := w.repo.FindWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook subscription
+func (w *webhooksUseCase) DeleteWebhook(ctx context.Context, id primitive.ObjectID) error {
+	return w.repo.DeleteWebhook(ctx, id)
+}
+
+// FindDeliveries retrieves the delivery log of a webhook
+func (w *webhooksUseCase) FindDeliveries(ctx context.Context, webhookID primitive.ObjectID) ([]*domain.Delivery, error) {
+	return w.repo.FindDeliveries(ctx, webhookID)
+}
+
+// FindDeadLetters retrieves all events that could not be delivered
+func (w *webhooksUseCase) FindDeadLetters(ctx context.Context) ([]*domain.DeadLetter, error) {
+	return w.repo.FindDeadLetters(ctx)
+}
+
+// RetryDeadLetter takes an event off the dead-letter queue and delivers it again
+func (w *webhooksUseCase) RetryDeadLetter(ctx context.Context, id primitive.ObjectID) error {
+	deadLetter, err := w.repo.TakeDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deadLetter == nil {
+		return mongo.ErrNoDocuments
+	}
+
+	webhook, err := w.repo.GetWebhook(ctx, deadLetter.WebhookID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		// The subscription is gone, so there is nowhere to deliver to
+		return mongo.ErrNoDocuments
+	}
+
+	if err := w.deliverer.Deliver(ctx, webhook, &deadLetter.Event); err != nil {
+		// Put the event back so it is not lost
+		if dlErr := w.repo.InsertDeadLetter(ctx, deadLetter); dlErr != nil {
+			return errors.Join(err, dlErr)
+		}
+		return err
+	}
+	return nil
+}
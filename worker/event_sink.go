@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"findApi/domain"
+	"io"
+	"os"
+	"sync"
+)
+
+// EventSink receives the events published from the outbox. Publish returns an
+// error when the event could not be handed over, so it is retried later.
+type EventSink interface {
+	Publish(ctx context.Context, event *domain.Event) error
+}
+
+// StdoutSink writes every event as a line of JSON, which is handy during development
+type StdoutSink struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewStdoutSink creates a sink that writes to standard output
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{out: os.Stdout}
+}
+
+// Publish writes the event to standard output
+func (s *StdoutSink) Publish(ctx context.Context, event *domain.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.NewEncoder(s.out).Encode(event)
+}
+
+// EventBus fans events out to in-process subscribers
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[chan *domain.Event]struct{}
+	closed      bool
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[chan *domain.Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every published event and a function
+// that ends the subscription. Events are dropped for subscribers whose buffer is full.
+// The channel is closed when the bus is.
+func (b *EventBus) Subscribe(buffer int) (<-chan *domain.Event, func()) {
+	ch := make(chan *domain.Event, buffer)
+
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subscribers[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Publish hands the event to every subscriber
+func (b *EventBus) Publish(ctx context.Context, event *domain.Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close closes the channel of every subscriber, ending their streams on
+// shutdown. Later subscribers receive a closed channel.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		close(ch)
+		delete(b.subscribers, ch)
+	}
+	b.closed = true
+}
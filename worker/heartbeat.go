@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat records when a worker last started a pass, so a worker that
+// stopped or hangs shows up in the readiness check
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+// Beat records that a pass starts now
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check fails when no pass has started within maxAge
+func (h *Heartbeat) Check(maxAge time.Duration) error {
+	last := h.last.Load()
+	if last == 0 {
+		return errors.New("not started")
+	}
+	if age := time.Since(time.Unix(0, last)); age > maxAge {
+		return fmt.Errorf("no pass for %s", age.Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Notifier tells someone about a reminder. Notify returns an error when the
+// reminder could not be handed over, so it is tried again later.
+type Notifier interface {
+	Notify(ctx context.Context, reminder *domain.Reminder) error
+}
+
+// reminderText describes a reminder in one line
+func reminderText(reminder *domain.Reminder) string {
+	text := fmt.Sprintf("%s of %s on %s", reminder.Kind, reminder.Username, reminder.Date)
+	if reminder.Years > 0 {
+		text += fmt.Sprintf(" (%d years)", reminder.Years)
+	}
+	return text
+}
+
+// LogNotifier writes reminders to the log
+type LogNotifier struct{}
+
+// Notify logs the reminder. The username is logged under its own key, so
+// the log handler masks it; the user id tells who the reminder is about.
+func (LogNotifier) Notify(ctx context.Context, reminder *domain.Reminder) error {
+	slog.InfoContext(ctx, "Reminder",
+		"kind", reminder.Kind,
+		"userId", reminder.UserID,
+		"username", reminder.Username,
+		"date", reminder.Date,
+		"years", reminder.Years,
+	)
+	return nil
+}
+
+// WebhookNotifier posts reminders as JSON to a URL, signed like user event webhooks
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to the URL with requests signed by the secret
+func NewWebhookNotifier(url, secret string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+// Notify posts the reminder and expects a 2xx response
+func (n *WebhookNotifier) Notify(ctx context.Context, reminder *domain.Reminder) error {
+	payload, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, "reminder."+reminder.Kind)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+encryptutil.SignHMAC([]byte(timestamp+"."+string(payload)), n.Secret))
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// MailNotifier stands in for an SMTP notifier. It renders each reminder as
+// an email message and writes it out instead of sending it.
+type MailNotifier struct {
+	From string
+	To   string
+	mu   sync.Mutex
+	out  io.Writer
+}
+
+// NewMailNotifier creates a mail notifier writing messages to standard output
+func NewMailNotifier(from, to string) *MailNotifier {
+	return &MailNotifier{From: from, To: to, out: os.Stdout}
+}
+
+// Notify writes the reminder as an email message
+func (n *MailNotifier) Notify(ctx context.Context, reminder *domain.Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.out, "From: %s\r\nTo: %s\r\nDate: %s\r\nSubject: Reminder: %s\r\n\r\n%s\r\n.\r\n",
+		n.From, n.To, time.Now().Format(time.RFC1123Z), reminderText(reminder), reminderText(reminder))
+	return err
+}
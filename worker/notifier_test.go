@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"findApi/domain"
+	"findApi/internal/logging"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogNotifierMasksUsername(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(logging.NewHandler(slog.NewJSONHandler(&buf, nil))))
+	defer slog.SetDefault(previous)
+
+	reminder := &domain.Reminder{UserID: "64b7f3a2c9e77a0012345678", Username: "Ada Lovelace", Kind: domain.ReminderBirthday, Date: "2025-12-10", Years: 35}
+	if err := (LogNotifier{}).Notify(context.Background(), reminder); err != nil {
+		t.Fatalf("Notify(): %v", err)
+	}
+
+	line := buf.String()
+	if strings.Contains(line, "Ada") || strings.Contains(line, "Lovelace") {
+		t.Errorf("Notify() logged the username: %s", line)
+	}
+	for _, want := range []string{reminder.UserID, reminder.Kind} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Notify() log lacks %q: %s", want, line)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"findApi/repository"
+	"log/slog"
+	"time"
+)
+
+// OutboxDispatcher publishes outbox entries to the sinks. An entry is only
+// marked as published once every sink has accepted it, so events are
+// delivered at least once and a sink may see an event more than once.
+type OutboxDispatcher struct {
+	Outbox    repository.OutboxRepo
+	Sinks     []EventSink
+	Interval  time.Duration
+	BatchSize int64
+
+	// Heartbeat is beaten at the start of every pass
+	Heartbeat Heartbeat
+}
+
+// Run polls the outbox every Interval until the context is cancelled
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.Heartbeat.Beat()
+		d.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch publishes pending entries in order, stopping at the first entry a
+// sink rejects so it is retried on the next pass
+func (d *OutboxDispatcher) dispatch(ctx context.Context) {
+	entries, err := d.Outbox.FindPending(ctx, d.BatchSize)
+	if err != nil {
+		slog.Error("Failed to read outbox", "error", err)
+		return
+	}
+
+	for _, entry := range entries {
+		for _, sink := range d.Sinks {
+			if err := sink.Publish(ctx, &entry.Event); err != nil {
+				slog.Error("Failed to publish event", "event", entry.Event.ID, "error", err)
+				return
+			}
+		}
+
+		if err := d.Outbox.MarkPublished(ctx, entry.ID); err != nil {
+			slog.Error("Failed to mark event as published", "event", entry.Event.ID, "error", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"findApi/domain"
+	"findApi/repository"
+	"findApi/usecase"
+	"log/slog"
+	"time"
+)
+
+// ReminderScheduler periodically sends the birthdays and anniversaries due
+// within LeadDays to every notifier. Each reminder is sent once.
+type ReminderScheduler struct {
+	UserUsecase usecase.UsersUseCase
+	Reminders   repository.RemindersRepo
+	Notifiers   []Notifier
+	Location    *time.Location
+	LeadDays    int
+	Interval    time.Duration
+
+	// Heartbeat is beaten at the start of every pass
+	Heartbeat Heartbeat
+}
+
+// Run checks for due reminders every Interval until the context is cancelled
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Heartbeat.Beat()
+		s.remind(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// remind sends the due reminders that were not sent yet
+func (s *ReminderScheduler) remind(ctx context.Context) {
+	reminders, err := s.UserUsecase.UpcomingReminders(ctx, time.Now().In(s.Location), s.LeadDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to find reminders", "error", err)
+		return
+	}
+
+	for _, reminder := range reminders {
+		// Claim the reminder first, so other instances skip it
+		claimed, err := s.Reminders.MarkNotified(ctx, reminder)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to record reminder", "error", err)
+			return
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.notify(ctx, reminder); err != nil {
+			slog.ErrorContext(ctx, "Failed to send reminder", "error", err)
+			// Release the claim so the reminder is sent on the next run, even
+			// when sending failed because of shutdown
+			if err := s.Reminders.UnmarkNotified(context.WithoutCancel(ctx), reminder); err != nil {
+				slog.ErrorContext(ctx, "Failed to release reminder", "error", err)
+			}
+		}
+	}
+}
+
+// notify hands the reminder to every notifier, stopping at the first failure
+func (s *ReminderScheduler) notify(ctx context.Context, reminder *domain.Reminder) error {
+	for _, notifier := range s.Notifiers {
+		if err := notifier.Notify(ctx, reminder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"context"
+	"findApi/internal/metrics"
+	"findApi/usecase"
+	"log/slog"
+	"time"
+)
+
+// RetentionJob periodically applies the retention policies of every address
+// book. With DryRun set it only logs what the policies would remove.
+type RetentionJob struct {
+	RetentionUsecase usecase.RetentionUseCase
+	DryRun           bool
+	Interval         time.Duration
+
+	// Heartbeat is beaten at the start of every pass
+	Heartbeat Heartbeat
+}
+
+// Run applies the policies every Interval until the context is cancelled
+func (j *RetentionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.Heartbeat.Beat()
+		j.apply(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply runs a single pass of the policies, logging the outcome of every rule
+// and recording it in the metrics
+func (j *RetentionJob) apply(ctx context.Context) {
+	start := time.Now()
+	report, err := j.RetentionUsecase.ApplyPolicies(ctx, j.DryRun)
+	metrics.ObserveRetention(start, err)
+	for _, result := range report.Results {
+		metrics.ObserveRetentionRule(result.Rule, result.Affected, j.DryRun)
+		switch {
+		case j.DryRun:
+			slog.InfoContext(ctx, "Retention rule would remove records", "addressBook", result.AddressBook, "rule", result.Rule, "affected", result.Affected, "cutoff", result.Cutoff)
+		case result.Affected > 0:
+			slog.InfoContext(ctx, "Retention rule removed records", "addressBook", result.AddressBook, "rule", result.Rule, "affected", result.Affected, "cutoff", result.Cutoff)
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to apply retention policies", "error", err)
+	}
+}
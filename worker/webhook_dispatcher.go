@@ -0,0 +1,247 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"findApi/bootstrap"
+	"findApi/domain"
+	"findApi/internal/encryptutil"
+	"findApi/repository"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// WebhookDispatcher delivers user lifecycle events to the subscribed webhooks.
+// Published events are queued in the database, one delivery per webhook, and
+// stay queued until they are delivered or moved to the dead-letter queue, so
+// no event is lost when the process stops. Failed deliveries are retried
+// with exponential backoff and dead-lettered once all attempts are used up.
+type WebhookDispatcher struct {
+	repo        repository.WebhooksRepo
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	interval    time.Duration
+	batchSize   int
+	stall       time.Duration
+
+	// Heartbeat is beaten at the start of every pass over the queue
+	Heartbeat Heartbeat
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher with the given repository and settings
+func NewWebhookDispatcher(repo repository.WebhooksRepo, env *bootstrap.Env) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: env.WEBHOOK_TIMEOUT},
+		maxAttempts: env.WEBHOOK_MAX_ATTEMPTS,
+		backoff:     env.WEBHOOK_RETRY_BACKOFF,
+		interval:    env.WEBHOOK_POLL_INTERVAL,
+		batchSize:   env.WEBHOOK_BATCH_SIZE,
+		stall:       env.HEALTH_STALL_TIMEOUT,
+	}
+}
+
+// Publish queues an event for delivery to every webhook subscribed to its
+// type. It only returns once the deliveries are stored, so the outbox keeps
+// the event until then.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event *domain.Event) error {
+	webhooks, err := d.repo.FindWebhooksForEvent(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+	return d.repo.EnqueueDeliveries(ctx, webhooks, event)
+}
+
+// Deliver queues an event for delivery to a single webhook
+func (d *WebhookDispatcher) Deliver(ctx context.Context, webhook *domain.Webhook, event *domain.Event) error {
+	return d.repo.EnqueueDeliveries(ctx, []*domain.Webhook{webhook}, event)
+}
+
+// Check fails when the queue has not been read for longer than the poll
+// interval and the stall timeout, which means deliveries have stopped
+func (d *WebhookDispatcher) Check() error {
+	return d.Heartbeat.Check(d.interval + d.stall)
+}
+
+// Run attempts the due deliveries every poll interval until the context is
+// cancelled, waiting for the attempts of a pass to finish before the next one
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.Heartbeat.Beat()
+		d.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch claims the due deliveries and attempts each of them once. A
+// delivery is leased for longer than an attempt can take, so other instances
+// leave it alone meanwhile and pick it up again should this one stop.
+func (d *WebhookDispatcher) dispatch(ctx context.Context) {
+	lease := d.client.Timeout + time.Minute
+	deliveries, err := d.repo.ClaimDeliveries(ctx, d.batchSize, lease)
+	if err != nil {
+		slog.Error("Failed to read webhook queue", "error", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, pending := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.attempt(ctx, pending)
+		}()
+	}
+	wg.Wait()
+}
+
+// attempt makes one attempt at a queued delivery, records it in the delivery
+// log and then removes the delivery from the queue when it succeeded or used
+// up its attempts, or schedules the next attempt with exponential backoff
+func (d *WebhookDispatcher) attempt(ctx context.Context, pending *domain.PendingDelivery) {
+	event := &pending.Event
+	webhook, err := d.repo.GetWebhook(ctx, pending.WebhookID)
+	if err != nil {
+		slog.Error("Failed to read webhook", "event", event.ID, "error", err)
+		return
+	}
+	if webhook == nil {
+		// The subscription is gone, so there is nowhere to deliver to
+		d.complete(ctx, pending)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to encode event", "event", event.ID, "error", err)
+		return
+	}
+
+	statusCode, err := d.send(ctx, webhook, event, payload)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown, the delivery is attempted again once its lease runs out
+		return
+	}
+	// The outcome of a finished attempt is recorded even when shutdown starts meanwhile
+	ctx = context.WithoutCancel(ctx)
+
+	attempts := pending.Attempts + 1
+	delivery := &domain.Delivery{
+		WebhookID:   webhook.ID,
+		EventID:     event.ID,
+		EventType:   event.Type,
+		Attempt:     attempts,
+		Status:      domain.DeliverySucceeded,
+		StatusCode:  statusCode,
+		AttemptedAt: time.Now().UTC(),
+	}
+	if err != nil {
+		delivery.Status = domain.DeliveryFailed
+		delivery.Error = err.Error()
+	}
+	if recErr := d.repo.InsertDelivery(ctx, delivery); recErr != nil {
+		slog.Error("Failed to record webhook delivery", "event", event.ID, "error", recErr)
+	}
+
+	switch {
+	case err == nil:
+		d.complete(ctx, pending)
+	case attempts >= d.maxAttempts:
+		// Only leave the queue once the event is safe in the dead-letter queue
+		if dlErr := d.deadLetter(ctx, webhook, event, err); dlErr == nil {
+			d.complete(ctx, pending)
+		}
+	default:
+		next := time.Now().Add(retryDelay(d.backoff, attempts))
+		if rsErr := d.repo.RescheduleDelivery(ctx, pending.ID, attempts, next); rsErr != nil {
+			slog.Error("Failed to reschedule webhook delivery", "event", event.ID, "error", rsErr)
+		}
+	}
+}
+
+// maxRetryBackoff caps the wait between delivery attempts
+const maxRetryBackoff = 24 * time.Hour
+
+// retryDelay returns the wait before the next attempt of a delivery that
+// failed attempts times: the backoff doubled on every retry, up to
+// maxRetryBackoff. Doubling stops at the cap, so many attempts cannot
+// overflow into a negative delay.
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := min(backoff, maxRetryBackoff)
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
+
+// complete removes a delivery from the queue
+func (d *WebhookDispatcher) complete(ctx context.Context, pending *domain.PendingDelivery) {
+	if err := d.repo.CompleteDelivery(ctx, pending.ID); err != nil {
+		slog.Error("Failed to remove webhook delivery from the queue", "event", pending.Event.ID, "error", err)
+	}
+}
+
+// deadLetter moves an event that could not be delivered to the dead-letter queue
+func (d *WebhookDispatcher) deadLetter(ctx context.Context, webhook *domain.Webhook, event *domain.Event, lastErr error) error {
+	deadLetter := &domain.DeadLetter{
+		WebhookID: webhook.ID,
+		Event:     *event,
+		LastError: lastErr.Error(),
+		FailedAt:  time.Now().UTC(),
+	}
+	if err := d.repo.InsertDeadLetter(ctx, deadLetter); err != nil {
+		slog.Error("Failed to dead-letter event", "event", event.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// send makes a single signed delivery attempt and returns the response status code
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *domain.Webhook, event *domain.Event, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	// Sign the timestamp together with the payload so a captured request cannot be replayed later
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := encryptutil.SignHMAC([]byte(timestamp+"."+string(payload)), webhook.Secret)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+signature)
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{"first retry", time.Second, 1, time.Second},
+		{"doubled", time.Second, 2, 2 * time.Second},
+		{"doubled again", time.Second, 4, 8 * time.Second},
+		{"just below the cap", time.Second, 17, 65536 * time.Second},
+		{"up to the cap", time.Second, 18, maxRetryBackoff},
+		{"shift would overflow", time.Second, 64, maxRetryBackoff},
+		{"far past overflow", time.Second, 1000, maxRetryBackoff},
+		{"long backoff", 10 * time.Hour, 40, maxRetryBackoff},
+		{"backoff above the cap", 48 * time.Hour, 1, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.backoff, tt.attempts); got != tt.want {
+				t.Errorf("retryDelay(%v, %d) = %v, want %v", tt.backoff, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}